@@ -0,0 +1,95 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "schh/internal/config"
+)
+
+func recordAudit(event string, host config.Host, label, sessionID, detail string) {
+    entry := config.AuditEntry{
+        Event:     event,
+        Host:      host.Name,
+        Target:    host.Target,
+        Label:     label,
+        SessionID: sessionID,
+        Detail:    detail,
+    }
+    if err := config.AppendAudit(entry); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to write audit log: %v\n", err)
+    }
+}
+
+func setupAudit(fs *flag.FlagSet) func(args []string) int {
+    sinceText := fs.String("since", "", "only show entries newer than a `duration or date`")
+    hostName := fs.String("host", "", "only show entries for `host-name`")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        var since time.Time
+        if *sinceText != "" {
+            parsed, err := parseSince(*sinceText)
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "Invalid --since value '%s': use a duration such as 2h or 7d, or a date such as 2024-05-01.\n", *sinceText)
+                return 1
+            }
+            since = parsed
+        }
+        return runAudit(since, *hostName)
+    }
+}
+
+func runAudit(since time.Time, hostName string) int {
+    if err := config.PruneAuditRetention(); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to prune audit log: %v\n", err)
+    }
+    entries, err := config.LoadAudit()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read audit log: %v\n", err)
+        return 1
+    }
+    matched := []config.AuditEntry{}
+    for _, e := range entries {
+        if e.Time.Before(since) || (hostName != "" && e.Host != hostName) {
+            continue
+        }
+        matched = append(matched, e)
+    }
+    if globals.json {
+        return printJSON(matched)
+    }
+    for _, e := range matched {
+        fields := []string{e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Event, e.Host}
+        if e.Target != "" && e.Target != e.Host {
+            fields = append(fields, e.Target)
+        }
+        if e.Label != "" {
+            fields = append(fields, e.Label)
+        }
+        if e.Detail != "" {
+            fields = append(fields, e.Detail)
+        }
+        fmt.Println(strings.Join(fields, "  "))
+    }
+    if len(matched) == 0 {
+        fmt.Println("No matching audit entries.")
+    }
+    return 0
+}
+
+func parseSince(value string) (time.Time, error) {
+    if d, err := config.ParseDuration(value); err == nil {
+        return time.Now().Add(-d), nil
+    }
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+    return time.ParseInLocation("2006-01-02", value, time.Local)
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+    "schh/internal/version"
+)
+
+type command struct {
+    name        string
+    args        string
+    summary     string
+    setup       func(fs *flag.FlagSet) func(args []string) int
+    subcommands []*command
+}
+
+type globalOptions struct {
+    config  string
+    backend string
+    verbose bool
+    json    bool
+}
+
+var (
+    globals globalOptions
+    active  *command
+    path    []string
+)
+
+func addGlobalFlags(fs *flag.FlagSet) {
+    fs.StringVar(&globals.config, "config", globals.config, "use `dir` instead of the default config directory")
+    fs.StringVar(&globals.backend, "backend", globals.backend, "session `backend`: screen or tmux")
+    fs.BoolVar(&globals.verbose, "v", globals.verbose, "print the backend commands schh runs")
+    fs.BoolVar(&globals.verbose, "verbose", globals.verbose, "print the backend commands schh runs")
+    fs.BoolVar(&globals.json, "json", globals.json, "print machine-readable JSON where supported")
+}
+
+func applyGlobals() error {
+    if globals.config != "" {
+        config.SetConfigDir(globals.config)
+    }
+    backend := globals.backend
+    if backend == "" {
+        backend = os.Getenv("SCHH_BACKEND")
+    }
+    if backend == "" {
+        if settings, err := config.LoadSettings(); err == nil {
+            backend = settings.Backend
+        }
+    }
+    if err := session.SetBackend(backend); err != nil {
+        return err
+    }
+    if globals.verbose {
+        session.SetTrace(os.Stderr)
+    }
+    return nil
+}
+
+func run(args []string) int {
+    top := newFlagSet("schh")
+    addGlobalFlags(top)
+    help := top.Bool("help", false, "show help")
+    top.BoolVar(help, "h", false, "show help")
+    showVersion := top.Bool("version", false, "print the version")
+
+    i := 0
+    for i < len(args) && strings.HasPrefix(args[i], "-") && args[i] != "-" {
+        name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+        f := top.Lookup(name)
+        if f == nil {
+            break
+        }
+        i++
+        if !hasValue && !isBoolFlag(f) {
+            i++
+        }
+    }
+    if err := top.Parse(args[:min(i, len(args))]); err != nil {
+        return flagError(top, nil, err)
+    }
+    args = args[min(i, len(args)):]
+    if *help {
+        printCommands(os.Stdout)
+        return 0
+    }
+    if *showVersion {
+        fmt.Println(version.Get())
+        return 0
+    }
+    if len(args) == 0 {
+        printUsage()
+        return 1
+    }
+
+    if args[0] == "--" {
+        return execute(connectCommand, []string{connectCommand.name}, args)
+    }
+    if args[0] == "help" {
+        return runHelp(args[1:])
+    }
+    cmd := findCommand(commands, args[0])
+    if cmd == nil {
+        return execute(connectCommand, []string{connectCommand.name}, args)
+    }
+    return execute(cmd, []string{cmd.name}, args[1:])
+}
+
+func execute(cmd *command, names []string, args []string) int {
+    if len(cmd.subcommands) > 0 && len(args) > 0 {
+        if sub := findCommand(cmd.subcommands, args[0]); sub != nil {
+            return execute(sub, append(names, sub.name), args[1:])
+        }
+    }
+    if cmd.setup == nil {
+        if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+            printCommandHelp(cmd, names)
+            if len(args) == 0 {
+                return 1
+            }
+            return 0
+        }
+        fmt.Fprintf(os.Stderr, "Unknown %s command '%s'.", strings.Join(names, " "), args[0])
+        if suggestion := suggest(args[0], commandNames(cmd.subcommands)); suggestion != "" {
+            fmt.Fprintf(os.Stderr, " Did you mean '%s'?", suggestion)
+        }
+        fmt.Fprintf(os.Stderr, "\nRun 'schh help %s' for usage.\n", strings.Join(names, " "))
+        return 1
+    }
+
+    fs := newFlagSet(strings.Join(names, " "))
+    addGlobalFlags(fs)
+    runFn := cmd.setup(fs)
+    positional, err := parseFlags(fs, args)
+    if err != nil {
+        return flagError(fs, append([]string{}, names...), err)
+    }
+    if err := applyGlobals(); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to select the session backend: %v\n", err)
+        return 1
+    }
+    active, path = cmd, names
+    if names[0] != "daemon" {
+        useDaemon()
+    }
+    if names[0] != "daemon" && names[0] != "migrate" {
+        session.OnLegacySessions(warnLegacySessions)
+    }
+    return runFn(positional)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(io.Discard)
+    fs.Usage = func() {}
+    return fs
+}
+
+func noFlags(run func(args []string) int) func(fs *flag.FlagSet) func(args []string) int {
+    return func(fs *flag.FlagSet) func(args []string) int {
+        return run
+    }
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil {
+            return nil, err
+        }
+        rest := fs.Args()
+        consumed := len(args) - len(rest)
+        if consumed > 0 && args[consumed-1] == "--" {
+            return append(positional, rest...), nil
+        }
+        if len(rest) == 0 {
+            return positional, nil
+        }
+        positional = append(positional, rest[0])
+        args = rest[1:]
+    }
+}
+
+func flagError(fs *flag.FlagSet, names []string, err error) int {
+    if errors.Is(err, flag.ErrHelp) {
+        if names == nil {
+            printCommands(os.Stdout)
+        } else {
+            printCommandHelp(lookupPath(names), names)
+        }
+        return 0
+    }
+    const undefined = "flag provided but not defined: -"
+    if name, ok := strings.CutPrefix(err.Error(), undefined); ok {
+        fmt.Fprintf(os.Stderr, "Unknown option '%s'.", flagName(name))
+        var known []string
+        fs.VisitAll(func(f *flag.Flag) {
+            known = append(known, f.Name)
+        })
+        if suggestion := suggest(name, known); suggestion != "" {
+            fmt.Fprintf(os.Stderr, " Did you mean '%s'?", flagName(suggestion))
+        }
+        fmt.Fprintln(os.Stderr)
+    } else if name, ok := strings.CutPrefix(err.Error(), "flag needs an argument: -"); ok {
+        fmt.Fprintf(os.Stderr, "%s requires a value.\n", flagName(name))
+    } else if rest, ok := strings.CutPrefix(err.Error(), "invalid value "); ok {
+        value, rest, _ := strings.Cut(rest, " for flag -")
+        name, reason, _ := strings.Cut(rest, ": ")
+        fmt.Fprintf(os.Stderr, "Invalid value %s for %s: %s.\n", value, flagName(name), reason)
+    } else {
+        fmt.Fprintf(os.Stderr, "%v\n", err)
+    }
+    if names == nil {
+        fmt.Fprintln(os.Stderr, "Run 'schh help' for usage.")
+    } else {
+        fmt.Fprintf(os.Stderr, "Run 'schh help %s' for usage.\n", strings.Join(names, " "))
+    }
+    return 1
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+    b, ok := f.Value.(interface{ IsBoolFlag() bool })
+    return ok && b.IsBoolFlag()
+}
+
+func flagName(name string) string {
+    if len(name) == 1 {
+        return "-" + name
+    }
+    return "--" + name
+}
+
+func findCommand(list []*command, name string) *command {
+    for _, cmd := range list {
+        if cmd.name == name {
+            return cmd
+        }
+    }
+    return nil
+}
+
+func lookupPath(names []string) *command {
+    if len(names) > 0 && names[0] == connectCommand.name {
+        return connectCommand
+    }
+    list := commands
+    var cmd *command
+    for _, name := range names {
+        cmd = findCommand(list, name)
+        if cmd == nil {
+            return nil
+        }
+        list = cmd.subcommands
+    }
+    return cmd
+}
+
+func commandNames(list []*command) []string {
+    names := make([]string, 0, len(list))
+    for _, cmd := range list {
+        names = append(names, cmd.name)
+    }
+    return names
+}
+
+func runHelp(args []string) int {
+    if len(args) == 0 {
+        printCommands(os.Stdout)
+        return 0
+    }
+    cmd := lookupPath(args)
+    if cmd == nil {
+        fmt.Fprintf(os.Stderr, "Unknown command '%s'.", strings.Join(args, " "))
+        if suggestion := suggest(args[len(args)-1], commandNames(commands)); suggestion != "" {
+            fmt.Fprintf(os.Stderr, " Did you mean '%s'?", suggestion)
+        }
+        fmt.Fprintln(os.Stderr)
+        return 1
+    }
+    printCommandHelp(cmd, args)
+    return 0
+}
+
+func printUsage() {
+    if active != nil {
+        fmt.Fprintf(os.Stderr, "Usage: %s\n", usageLine(active, path))
+        fmt.Fprintf(os.Stderr, "Run 'schh help %s' for details.\n", strings.Join(path, " "))
+        return
+    }
+    printCommands(os.Stderr)
+}
+
+func printCommands(w io.Writer) {
+    fmt.Fprintf(w, "Usage:\n")
+    fmt.Fprintf(w, "  schh <host-name> [session-name] [flags]\n")
+    fmt.Fprintf(w, "  schh <command> [arguments] [flags]\n")
+    fmt.Fprintf(w, "\nCommands:\n")
+    for _, cmd := range commands {
+        fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+    }
+    fmt.Fprintf(w, "\nGlobal flags:\n")
+    printFlags(w, globalFlagSet())
+    fmt.Fprintf(w, "\nRun 'schh help <command>' for the flags of a command.\n")
+}
+
+func printCommandHelp(cmd *command, names []string) {
+    w := os.Stdout
+    fmt.Fprintf(w, "Usage: %s\n\n%s\n", usageLine(cmd, names), cmd.summary)
+    if len(cmd.subcommands) > 0 {
+        fmt.Fprintf(w, "\nCommands:\n")
+        for _, sub := range cmd.subcommands {
+            fmt.Fprintf(w, "  %-10s %s\n", sub.name, sub.summary)
+        }
+    }
+    if cmd.setup == nil {
+        return
+    }
+    fs := newFlagSet(strings.Join(names, " "))
+    cmd.setup(fs)
+    if hasFlags(fs) {
+        fmt.Fprintf(w, "\nFlags:\n")
+        printFlags(w, fs)
+    }
+    fmt.Fprintf(w, "\nGlobal flags:\n")
+    printFlags(w, globalFlagSet())
+}
+
+func usageLine(cmd *command, names []string) string {
+    parts := []string{"schh"}
+    if cmd != connectCommand {
+        parts = append(parts, names...)
+    }
+    if cmd.args != "" {
+        parts = append(parts, cmd.args)
+    }
+    if cmd.setup != nil {
+        parts = append(parts, "[flags]")
+    }
+    return strings.Join(parts, " ")
+}
+
+func globalFlagSet() *flag.FlagSet {
+    fs := newFlagSet("schh")
+    addGlobalFlags(fs)
+    return fs
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+    found := false
+    fs.VisitAll(func(*flag.Flag) { found = true })
+    return found
+}
+
+func printFlags(w io.Writer, fs *flag.FlagSet) {
+    type entry struct {
+        names []string
+        flag  *flag.Flag
+    }
+    var entries []*entry
+    fs.VisitAll(func(f *flag.Flag) {
+        for _, e := range entries {
+            if e.flag.Value == f.Value {
+                e.names = append(e.names, flagName(f.Name))
+                return
+            }
+        }
+        entries = append(entries, &entry{names: []string{flagName(f.Name)}, flag: f})
+    })
+    for _, e := range entries {
+        sort.Slice(e.names, func(i, j int) bool { return len(e.names[i]) < len(e.names[j]) })
+        placeholder, usage := flag.UnquoteUsage(e.flag)
+        label := strings.Join(e.names, ", ")
+        if placeholder != "" {
+            label += " <" + placeholder + ">"
+        }
+        fmt.Fprintf(w, "  %-28s %s\n", label, usage)
+    }
+}
+
+func suggest(name string, candidates []string) string {
+    limit := 2
+    if len(name) < 5 {
+        limit = 1
+    }
+    best := ""
+    for _, candidate := range candidates {
+        if d := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); d <= limit {
+            best, limit = candidate, d-1
+        }
+    }
+    return best
+}
+
+func levenshtein(a, b string) int {
+    ra, rb := []rune(a), []rune(b)
+    d := make([][]int, len(ra)+1)
+    for i := range d {
+        d[i] = make([]int, len(rb)+1)
+        d[i][0] = i
+    }
+    for j := range d[0] {
+        d[0][j] = j
+    }
+    for i := 1; i <= len(ra); i++ {
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+            if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+                d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+            }
+        }
+    }
+    return d[len(ra)][len(rb)]
+}
+
+type listValue []string
+
+func (l *listValue) String() string {
+    return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+    items := splitList(value)
+    if len(items) == 0 {
+        return errors.New("value cannot be empty")
+    }
+    *l = append(*l, items...)
+    return nil
+}
+
+type durationValue time.Duration
+
+func (d *durationValue) String() string {
+    return time.Duration(*d).String()
+}
+
+func (d *durationValue) Set(value string) error {
+    parsed, err := config.ParseDuration(value)
+    if err != nil {
+        return err
+    }
+    *d = durationValue(parsed)
+    return nil
+}
+
+func printJSON(v any) int {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to encode JSON: %v\n", err)
+        return 1
+    }
+    fmt.Println(string(data))
+    return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+
+    "schh/internal/config"
+    "schh/internal/daemon"
+    "schh/internal/session"
+)
+
+func runDaemonStatus(args []string) int {
+    if len(args) > 0 {
+        fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+        printUsage()
+        return 1
+    }
+    client, err := daemon.Connect()
+    if err != nil {
+        fmt.Println("The schh daemon is not running.")
+        return 1
+    }
+    ping, err := client.Ping()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "The schh daemon is not responding: %v\n", err)
+        return 1
+    }
+    sessions, err := client.Sessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "The schh daemon is not responding: %v\n", err)
+        return 1
+    }
+    path, _ := daemon.SocketPath()
+    fmt.Printf("The schh daemon is running on %s with the %s backend and tracks %d session(s).\n", path, ping.Backend, len(sessions))
+    return 0
+}
+
+func runDaemon(args []string) int {
+    if len(args) > 0 {
+        fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+        printUsage()
+        return 1
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    server := &daemon.Server{
+        Index:  daemon.NewIndex(),
+        Start:  daemonStart,
+        Kill:   daemonKill,
+        Logger: log.New(os.Stderr, "schh daemon: ", log.LstdFlags),
+    }
+    if err := server.Serve(ctx); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to run the daemon: %v\n", err)
+        return 1
+    }
+    return 0
+}
+
+func useDaemon() {
+    if os.Getenv("SCHH_NO_DAEMON") != "" {
+        return
+    }
+    var client *daemon.Client
+    var connect sync.Once
+    session.UseLister(func() ([]session.Info, error) {
+        connect.Do(func() {
+            if c, err := daemon.Connect(); err == nil && sameBackend(c) {
+                client = c
+            }
+        })
+        if client == nil {
+            return nil, daemon.ErrNotRunning
+        }
+        return client.Sessions()
+    })
+}
+
+func sameBackend(client *daemon.Client) bool {
+    ping, err := client.Ping()
+    return err == nil && ping.Backend == session.BackendName()
+}
+
+func daemonStart(hostName, label string) (string, error) {
+    host, err := daemonHost(hostName)
+    if err != nil {
+        return "", err
+    }
+    if label == "" {
+        label = session.GenerateSessionLabel()
+    }
+    label = session.SanitizeToken(label)
+    sessionID, err := session.BuildSessionID(host.Name, label)
+    if err != nil {
+        return "", err
+    }
+    if _, err := daemonSession(sessionID); err == nil {
+        return "", fmt.Errorf("%w: %s on %s", daemon.ErrSessionExists, label, host.Name)
+    } else if !errors.Is(err, daemon.ErrSessionNotFound) {
+        return "", err
+    }
+    if err := startSession(host, sessionID, label, nil); err != nil {
+        return "", err
+    }
+    return sessionID, nil
+}
+
+func daemonKill(hostName, label string) error {
+    host, err := daemonHost(hostName)
+    if err != nil {
+        return err
+    }
+    sessionID, err := session.BuildSessionID(host.Name, label)
+    if err != nil {
+        return err
+    }
+    running, err := daemonSession(sessionID)
+    if err != nil {
+        return err
+    }
+    return killSession(host, running, "")
+}
+
+func daemonHost(name string) (config.Host, error) {
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        return config.Host{}, err
+    }
+    host := config.FindHost(hosts, name)
+    if host == nil {
+        return config.Host{}, fmt.Errorf("%w: %s", config.ErrHostNotFound, name)
+    }
+    return *host, nil
+}
+
+func daemonSession(sessionID string) (session.Info, error) {
+    sessions, err := session.ScanSessions()
+    if err != nil {
+        return session.Info{}, err
+    }
+    for _, s := range sessions {
+        if s.Name == sessionID {
+            return s, nil
+        }
+    }
+    return session.Info{}, fmt.Errorf("%w: %s", daemon.ErrSessionNotFound, sessionID)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/exporter"
+)
+
+func setupHostExport(fs *flag.FlagSet) func(args []string) int {
+    format := fs.String("format", "ssh-config", "output `format`: "+strings.Join(exporter.Formats, ", "))
+    output := fs.String("output", "", "write to `file` instead of standard output")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runHostExport(*format, *output)
+    }
+}
+
+func runHostExport(format, output string) int {
+    known := false
+    for _, f := range exporter.Formats {
+        known = known || f == format
+    }
+    if !known {
+        fmt.Fprintf(os.Stderr, "Unknown export format '%s' (expected %s).\n", format, strings.Join(exporter.Formats, ", "))
+        return 1
+    }
+
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to load configured hosts: %v\n", err)
+        return 1
+    }
+    if output == "" {
+        if err := exporter.Write(os.Stdout, format, hosts); err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to export hosts: %v\n", err)
+            return 1
+        }
+        return 0
+    }
+    var buf bytes.Buffer
+    if err := exporter.Write(&buf, format, hosts); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to export hosts: %v\n", err)
+        return 1
+    }
+    if err := config.WriteFileAtomic(output, buf.Bytes(), 0o644); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to write '%s': %v\n", output, err)
+        return 1
+    }
+    fmt.Printf("Exported %d host(s) to '%s'.\n", len(hosts), output)
+    return 0
+}
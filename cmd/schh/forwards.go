@@ -0,0 +1,86 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+func runForwards(args []string) int {
+    if len(args) != 1 {
+        fmt.Fprintln(os.Stderr, "Please provide the host name.")
+        printUsage()
+        return 1
+    }
+    host, code := loadHost(args[0])
+    if host == nil {
+        return code
+    }
+
+    names := make([]string, 0, len(host.Forwards))
+    for name := range host.Forwards {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    fmt.Printf("Forward profiles for %s:\n", host.Name)
+    if len(names) == 0 {
+        fmt.Println("  (none)")
+    }
+    for _, name := range names {
+        fmt.Printf("  - %s: %s\n", name, describeForward(host.Forwards[name]))
+    }
+
+    all, err := session.ListAllSessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    running := make(map[string]bool, len(all))
+    for _, s := range all {
+        running[s.Name] = true
+    }
+    if err := config.PruneSessionRecords(running); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to update session records: %v\n", err)
+    }
+    records, err := config.LoadSessionRecords()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read session records: %v\n", err)
+        return 1
+    }
+
+    sessions, err := session.ListSessionsForHost(host.Name)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    fmt.Printf("\nActive tunnels for %s:\n", host.Name)
+    found := false
+    for _, s := range sessions {
+        record, ok := records[s.Name]
+        if !ok || len(record.Forwards) == 0 {
+            continue
+        }
+        found = true
+        described := make([]string, 0, len(record.Forwards))
+        for _, name := range record.Forwards {
+            described = append(described, fmt.Sprintf("%s (%s)", name, describeForward(host.Forwards[name])))
+        }
+        fmt.Printf("  - %s: %s\n", s.Label, strings.Join(described, ", "))
+    }
+    if !found {
+        fmt.Println("  (none)")
+    }
+    return 0
+}
+
+func describeForward(spec string) string {
+    forward, err := config.ParseForward(spec)
+    if err != nil {
+        return "no longer configured"
+    }
+    return forward.String()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+    "schh/internal/ui"
+)
+
+type expiredSession struct {
+    host   config.Host
+    info   session.Info
+    record config.SessionRecord
+    reason string
+}
+
+func setupGC(fs *flag.FlagSet) func(args []string) int {
+    expired := fs.Bool("expired", false, "also kill sessions past their idle or age limits")
+    dryRun := fs.Bool("dry-run", false, "show what would be cleaned up without changing anything")
+    assumeYes := fs.Bool("yes", false, "kill expired sessions without asking")
+    fs.BoolVar(assumeYes, "y", false, "kill expired sessions without asking")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runGC(*expired, *dryRun, *assumeYes)
+    }
+}
+
+func runGC(expired, dryRun, assumeYes bool) int {
+    all, err := session.ListAllSessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    running := make(map[string]bool, len(all))
+    var attached []string
+    for _, s := range all {
+        running[s.Name] = true
+        if s.Attached {
+            attached = append(attached, s.Name)
+        }
+    }
+    if !dryRun {
+        if err := config.PruneSessionRecords(running); err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to clean up session records: %v\n", err)
+            return 1
+        }
+        if err := config.MarkAttached(attached, time.Now()); err != nil {
+            fmt.Fprintf(os.Stderr, "Warning: unable to record attached sessions: %v\n", err)
+        }
+        if err := config.PruneAuditRetention(); err != nil {
+            fmt.Fprintf(os.Stderr, "Warning: unable to prune audit log: %v\n", err)
+        }
+    }
+    if !expired {
+        fmt.Println("Session records cleaned up. Use --expired to look for sessions past their limits.")
+        return 0
+    }
+
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load hosts: %v\n", err)
+        return 1
+    }
+    byToken := make(map[string]config.Host, len(hosts))
+    for _, h := range hosts {
+        byToken[session.SanitizeToken(h.Name)] = h
+    }
+    records, err := config.LoadSessionRecords()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read session records: %v\n", err)
+        return 1
+    }
+
+    now := time.Now()
+    var candidates []expiredSession
+    untracked := 0
+    for _, s := range all {
+        host, ok := byToken[s.HostToken]
+        if !ok || (host.IdleTimeout == "" && host.MaxAge == "") {
+            continue
+        }
+        record, ok := records[s.Name]
+        if !ok {
+            record = config.SessionRecord{ID: s.Name, Host: host.Name, Label: s.Label, Created: now}
+            if !dryRun {
+                if err := config.SaveSessionRecord(record); err != nil {
+                    fmt.Fprintf(os.Stderr, "Warning: unable to record session: %v\n", err)
+                }
+            }
+            untracked++
+            continue
+        }
+        if reason := config.ExpiryReason(host, record, s.Attached, now); reason != "" {
+            candidates = append(candidates, expiredSession{host: host, info: s, record: record, reason: reason})
+        }
+    }
+    if untracked > 0 {
+        fmt.Printf("%d session(s) had no record yet; their age is counted from now.\n", untracked)
+    }
+    if len(candidates) == 0 {
+        fmt.Println("No expired sessions.")
+        return 0
+    }
+
+    fmt.Println("Expired sessions:")
+    for _, c := range candidates {
+        fmt.Printf("  - %s/%s: %s (started %s, last active %s)\n",
+            c.host.Name, c.info.Label, c.reason,
+            c.record.Created.Local().Format("2006-01-02 15:04"),
+            c.record.LastActive().Local().Format("2006-01-02 15:04"))
+    }
+    if dryRun {
+        return 0
+    }
+    if !assumeYes {
+        confirmed, err := ui.Confirm(fmt.Sprintf("Kill %d expired session(s)?", len(candidates)), os.Stdin, os.Stdout)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to read answer: %v\n", err)
+            return 1
+        }
+        if !confirmed {
+            fmt.Println("No sessions killed.")
+            return 0
+        }
+    }
+
+    failed := 0
+    for _, c := range candidates {
+        if err := killSession(c.host, c.info, "expired: "+c.reason); err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to kill %s/%s: %v\n", c.host.Name, c.info.Label, err)
+            failed++
+            continue
+        }
+        fmt.Printf("Session '%s' on '%s' killed.\n", c.info.Label, c.host.Name)
+    }
+    if failed > 0 {
+        return 1
+    }
+    return 0
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+    "bufio"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/session"
+    "schh/internal/ui"
+)
+
+type hostChange struct {
+    changes *[][2]string
+    kind    string
+}
+
+func (c hostChange) String() string {
+    return ""
+}
+
+func (c hostChange) Set(value string) error {
+    switch c.kind {
+    case "target":
+        if containsWhitespace(value) || value == "" {
+            return errors.New("host names and targets cannot contain spaces")
+        }
+        *c.changes = append(*c.changes, [2]string{"target", value})
+    case "set":
+        key, val, ok := strings.Cut(value, "=")
+        if !ok {
+            return fmt.Errorf("expected key=value, got '%s'", value)
+        }
+        *c.changes = append(*c.changes, [2]string{key, val})
+    case "unset":
+        *c.changes = append(*c.changes, [2]string{value, ""})
+    }
+    return nil
+}
+
+func setupHostEdit(fs *flag.FlagSet) func(args []string) int {
+    var changes [][2]string
+    fs.Var(hostChange{&changes, "target"}, "target", "change the connection `target`")
+    fs.Var(hostChange{&changes, "set"}, "set", "set an attribute, as `key=value` (repeatable)")
+    fs.Var(hostChange{&changes, "unset"}, "unset", "clear the attribute `key` (repeatable)")
+    return func(args []string) int {
+        if len(args) != 1 {
+            fmt.Fprintln(os.Stderr, "Please provide the host name to edit.")
+            printUsage()
+            return 1
+        }
+        return runHostEdit(args[0], changes)
+    }
+}
+
+func runHostEdit(name string, changes [][2]string) int {
+    var update func(*config.Host) error
+    if len(changes) == 0 {
+        update = editHostInEditor
+    } else {
+        update = func(h *config.Host) error {
+            for _, change := range changes {
+                if change[0] == "target" {
+                    h.Target = change[1]
+                    continue
+                }
+                if err := config.SetHostAttr(h, change[0], change[1]); err != nil {
+                    return err
+                }
+            }
+            return nil
+        }
+    }
+
+    var edited config.Host
+    record := func(h *config.Host) error {
+        if err := update(h); err != nil {
+            return err
+        }
+        edited = *h
+        return nil
+    }
+    if err := config.UpdateHost(name, record); err != nil {
+        switch {
+        case errors.Is(err, config.ErrHostNotFound):
+            fmt.Fprintf(os.Stderr, "Host '%s' was not found.\n", name)
+        case errors.Is(err, config.ErrReadOnlySource):
+            fmt.Fprintf(os.Stderr, "Host '%s' comes from a shared source and cannot be edited here (%v).\n", name, err)
+        default:
+            fmt.Fprintf(os.Stderr, "Unable to edit host '%s': %v\n", name, err)
+        }
+        return 1
+    }
+    recordAudit(config.AuditHostEdit, edited, "", "", config.FormatHost(edited))
+    fmt.Printf("Host '%s' updated.\n", name)
+    return 0
+}
+
+func editHostInEditor(h *config.Host) error {
+    file, err := os.CreateTemp("", "schh-host-*.txt")
+    if err != nil {
+        return err
+    }
+    path := file.Name()
+    defer os.Remove(path)
+    fmt.Fprintf(file, "# Edit the record for '%s' and save. Lines starting with # are ignored.\n", h.Name)
+    fmt.Fprintln(file, "# Format: name target [key=value ...]")
+    fmt.Fprintln(file, config.FormatHost(*h))
+    if err := file.Close(); err != nil {
+        return err
+    }
+
+    editor := os.Getenv("VISUAL")
+    if editor == "" {
+        editor = os.Getenv("EDITOR")
+    }
+    if editor == "" {
+        editor = "vi"
+    }
+    cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+    cmd.Stdin = os.Stdin
+    cmd.Stdout = os.Stdout
+    cmd.Stderr = os.Stderr
+    if err := cmd.Run(); err != nil {
+        return fmt.Errorf("editor failed: %w", err)
+    }
+
+    edited, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer edited.Close()
+    var records []string
+    scanner := bufio.NewScanner(edited)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line != "" && !strings.HasPrefix(line, "#") {
+            records = append(records, line)
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    if len(records) != 1 {
+        return fmt.Errorf("expected exactly one host record, found %d", len(records))
+    }
+    updated, err := config.ParseHost(records[0])
+    if err != nil {
+        return err
+    }
+    updated.Source = h.Source
+    *h = updated
+    return nil
+}
+
+func setupHostRename(fs *flag.FlagSet) func(args []string) int {
+    assumeYes := fs.Bool("yes", false, "rename running sessions without asking")
+    fs.BoolVar(assumeYes, "y", false, "rename running sessions without asking")
+    return func(args []string) int {
+        return runHostRename(args, *assumeYes)
+    }
+}
+
+func runHostRename(names []string, assumeYes bool) int {
+    if len(names) != 2 {
+        fmt.Fprintln(os.Stderr, "Please provide the current and the new host name.")
+        printUsage()
+        return 1
+    }
+    oldName, newName := names[0], names[1]
+    if containsWhitespace(newName) {
+        fmt.Fprintln(os.Stderr, "Host names and targets cannot contain spaces.")
+        return 1
+    }
+
+    if err := config.RenameHost(oldName, newName); err != nil {
+        switch {
+        case errors.Is(err, config.ErrHostNotFound):
+            fmt.Fprintf(os.Stderr, "Host '%s' was not found.\n", oldName)
+        case errors.Is(err, config.ErrHostExists):
+            fmt.Fprintf(os.Stderr, "Host '%s' already exists.\n", newName)
+        case errors.Is(err, config.ErrReservedName):
+            fmt.Fprintf(os.Stderr, "'%s' is a schh command; pick another host name.\n", newName)
+        case errors.Is(err, config.ErrReadOnlySource):
+            fmt.Fprintf(os.Stderr, "Host '%s' comes from a shared source and cannot be renamed here (%v).\n", oldName, err)
+        default:
+            fmt.Fprintf(os.Stderr, "Unable to rename host '%s': %v\n", oldName, err)
+        }
+        return 1
+    }
+    renamed := config.Host{Name: newName}
+    if hosts, err := config.LoadHosts(); err == nil {
+        if h := config.FindHost(hosts, newName); h != nil {
+            renamed = *h
+        }
+    }
+    recordAudit(config.AuditHostRename, renamed, "", "", "renamed from "+oldName)
+    fmt.Printf("Host '%s' renamed to '%s'.\n", oldName, newName)
+    if _, err := config.MoveLastSessionLabel(oldName, newName); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to move recent sessions: %v\n", err)
+    }
+
+    if session.SanitizeToken(oldName) == session.SanitizeToken(newName) {
+        return 0
+    }
+    sessions, err := session.ListSessionsForHost(oldName)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to read active sessions: %v\n", err)
+        return 0
+    }
+    if len(sessions) == 0 {
+        return 0
+    }
+    if !assumeYes {
+        question := fmt.Sprintf("Rename %d running session(s) to the '%s' prefix?", len(sessions), newName)
+        confirmed, err := ui.Confirm(question, os.Stdin, os.Stdout)
+        if err != nil || !confirmed {
+            fmt.Printf("Running sessions keep the '%s' prefix.\n", oldName)
+            return 0
+        }
+    }
+    status := 0
+    for _, s := range sessions {
+        newID, err := session.BuildSessionID(newName, s.Label)
+        if err == nil {
+            err = session.RenameSession(s.ID, newID)
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to rename session '%s': %v\n", s.Label, err)
+            status = 1
+            continue
+        }
+        if err := config.RenameSessionRecord(s.Name, newID); err != nil {
+            fmt.Fprintf(os.Stderr, "Warning: unable to move the record of session '%s': %v\n", s.Label, err)
+        }
+        fmt.Printf("Session '%s' moved to '%s'.\n", s.Label, newName)
+    }
+    return status
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "schh/internal/config"
+    "schh/internal/importer"
+)
+
+func setupHostImport(fs *flag.FlagSet) func(args []string) int {
+    prefix := fs.String("prefix", "", "prepend `prefix` to every imported host name")
+    return func(args []string) int {
+        if len(args) == 0 {
+            fmt.Fprintln(os.Stderr, "Please provide the format to import from.")
+            printUsage()
+            return 1
+        }
+        if len(args) == 1 {
+            fmt.Fprintln(os.Stderr, "Please provide the file to import.")
+            printUsage()
+            return 1
+        }
+        if len(args) > 2 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runHostImport(args[0], args[1], *prefix)
+    }
+}
+
+func runHostImport(format, path, prefix string) int {
+    if containsWhitespace(prefix) {
+        fmt.Fprintln(os.Stderr, "Host names and targets cannot contain spaces.")
+        return 1
+    }
+
+    var imported []config.Host
+    var err error
+    switch format {
+    case "ansible":
+        imported, err = importer.Ansible(path, prefix)
+    case "ssh-config":
+        imported, err = importer.SSHConfig(path, prefix)
+    default:
+        fmt.Fprintf(os.Stderr, "Unknown import format '%s'.\n", format)
+        printUsage()
+        return 1
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read '%s': %v\n", path, err)
+        return 1
+    }
+
+    existing, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to load configured hosts: %v\n", err)
+        return 1
+    }
+    var added []config.Host
+    for _, h := range imported {
+        if config.FindHost(existing, h.Name) != nil {
+            fmt.Printf("Skipping '%s': already configured.\n", h.Name)
+            continue
+        }
+        if err := config.CheckNewHost(existing, h.Name); err != nil {
+            fmt.Printf("Skipping '%s': %v.\n", h.Name, err)
+            continue
+        }
+        if err := config.ValidateTarget(h.Target); err != nil {
+            fmt.Printf("Skipping '%s': %v.\n", h.Name, err)
+            continue
+        }
+        added = append(added, h)
+        existing = append(existing, h)
+    }
+    if len(added) == 0 {
+        fmt.Println("No new hosts to import.")
+        return 0
+    }
+    if err := config.AddHosts(added); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to save imported hosts: %v\n", err)
+        return 1
+    }
+    for _, h := range added {
+        recordAudit(config.AuditHostAdd, h, "", "", "imported from "+path)
+    }
+    fmt.Printf("Imported %d host(s) from '%s'.\n", len(added), path)
+    return 0
+}
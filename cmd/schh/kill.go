@@ -0,0 +1,55 @@
+package main
+
+import (
+    "fmt"
+    "os"
+
+    "schh/internal/config"
+    "schh/internal/hook"
+    "schh/internal/session"
+)
+
+func runKill(args []string) int {
+    if len(args) != 2 {
+        fmt.Fprintln(os.Stderr, "Please provide a host name and a session name.")
+        printUsage()
+        return 1
+    }
+
+    host, code := loadHost(args[0])
+    if host == nil {
+        return code
+    }
+    running, code := findRunningSession(*host, args[1])
+    if running == nil {
+        return code
+    }
+
+    if err := killSession(*host, *running, ""); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to kill session: %v\n", err)
+        return 1
+    }
+    fmt.Printf("Session '%s' on '%s' killed.\n", running.Label, host.Name)
+    return 0
+}
+
+func killSession(host config.Host, running session.Info, reason string) error {
+    if err := session.KillSession(running.ID); err != nil {
+        return err
+    }
+    if err := config.RemoveScreenrc(running.Name); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to remove session screenrc: %v\n", err)
+    }
+    recordAudit(config.AuditKill, host, running.Label, running.Name, reason)
+
+    settings, err := config.LoadSettings()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to load settings: %v\n", err)
+        return nil
+    }
+    hookCtx := hook.Context{Host: host.Name, Target: host.Target, Label: running.Label, SessionID: running.Name}
+    if err := hook.Run(hook.OnKill, config.HookCommands(settings, host, hook.OnKill), hookCtx); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+    }
+    return nil
+}
@@ -2,11 +2,15 @@ package main
 
 import (
     "errors"
+    "flag"
     "fmt"
     "os"
     "strings"
+    "time"
 
     "schh/internal/config"
+    "schh/internal/connect"
+    "schh/internal/hook"
     "schh/internal/session"
     "schh/internal/ui"
 )
@@ -15,59 +19,128 @@ func main() {
     os.Exit(run(os.Args[1:]))
 }
 
-func run(args []string) int {
-    if len(args) == 0 {
+var (
+    connectCommand *command
+    commands       []*command
+)
+
+func init() {
+    connectCommand = &command{
+        name:    "connect",
+        args:    "<host-name> [session-name]",
+        summary: "Attach to a session on a host, creating it if needed",
+        setup:   setupConnect,
+    }
+    commands = []*command{
+        connectCommand,
+        {name: "start", args: "<host-name> [session-name]", summary: "Start a session without the interactive picker", setup: setupStart},
+        {name: "host", args: "<command>", summary: "Manage configured hosts", subcommands: []*command{
+            {name: "add", args: "<name> [target]", summary: "Add a host", setup: noFlags(runHostAdd)},
+            {name: "remove", args: "<name>", summary: "Remove a host", setup: noFlags(runHostRemove)},
+            {name: "edit", args: "<name>", summary: "Change a host, in $EDITOR unless --target, --set or --unset is given", setup: setupHostEdit},
+            {name: "rename", args: "<old> <new>", summary: "Rename a host and optionally its running sessions", setup: setupHostRename},
+            {name: "list", summary: "List configured hosts", setup: setupHostList},
+            {name: "import", args: "ansible|ssh-config <file>", summary: "Import hosts from an inventory or ssh config", setup: setupHostImport},
+            {name: "export", summary: "Export hosts as ssh-config, json or csv", setup: setupHostExport},
+        }},
+        {name: "status", summary: "Summarise running sessions per host", setup: setupStatus},
+        {name: "current", summary: "Print the host and session of the current session", setup: setupCurrent},
+        {name: "watch", summary: "Stream session start, attach, detach and end events", setup: setupWatch},
+        {name: "peek", args: "<host-name> <session-name>", summary: "Print the last lines of a session without attaching", setup: setupPeek},
+        {name: "snapshot", args: "<host-name> <session-name>", summary: "Save the scrollback of a session to a file", setup: noFlags(runSnapshot)},
+        {name: "kill", args: "<host-name> <session-name>", summary: "Kill a session", setup: noFlags(runKill)},
+        {name: "gc", summary: "Clean up session records and expired sessions", setup: setupGC},
+        {name: "share", args: "<host-name> <session-name>", summary: "Let other users join a session (screen only)", setup: setupShare},
+        {name: "join", args: "<owner>/<host-name>/<session-name>", summary: "Join a session shared by another user (screen only)", setup: noFlags(runJoin)},
+        {name: "forwards", args: "<host-name>", summary: "List forward profiles and active tunnels", setup: noFlags(runForwards)},
+        {name: "master", args: "status|stop <host-name>", summary: "Check or stop a shared ssh connection", setup: noFlags(runMaster)},
+        {name: "migrate", summary: "Rename sessions to the current naming scheme", setup: setupMigrate},
+        {name: "audit", summary: "Show the audit log", setup: setupAudit},
+        {name: "version", summary: "Print version, build and environment details for bug reports", setup: noFlags(runVersion)},
+        {name: "upgrade", summary: "Update schh from the configured release feed", setup: setupUpgrade},
+        {name: "daemon", args: "[status]", summary: "Run the session index daemon in the foreground", setup: noFlags(runDaemon), subcommands: []*command{
+            {name: "status", summary: "Report whether the daemon is running", setup: noFlags(runDaemonStatus)},
+        }},
+    }
+    config.ReserveHostNames(append(commandNames(commands), "help")...)
+}
+
+type connectOptions struct {
+    list     bool
+    last     bool
+    shared   bool
+    steal    bool
+    detach   bool
+    forwards []string
+}
+
+func setupConnect(fs *flag.FlagSet) func(args []string) int {
+    var opts connectOptions
+    var forwards listValue
+    fs.BoolVar(&opts.list, "list", false, "list the running sessions for the host")
+    fs.BoolVar(&opts.last, "last", false, "attach to the most recently used session")
+    fs.BoolVar(&opts.shared, "shared", false, "attach alongside other displays")
+    fs.BoolVar(&opts.steal, "steal", false, "detach other displays before attaching")
+    fs.BoolVar(&opts.detach, "power-detach", false, "detach and log out other displays before attaching")
+    fs.Var(&forwards, "forward", "open the forward profile `name` for new sessions (repeatable)")
+    return func(args []string) int {
+        opts.forwards = forwards
+        return runConnect(args, opts)
+    }
+}
+
+func runConnect(positional []string, opts connectOptions) int {
+    if len(positional) == 0 {
+        fmt.Fprintln(os.Stderr, "Please provide a host name.")
         printUsage()
         return 1
     }
-
-    if args[0] == "host" {
-        return runHostCommand(args[1:])
+    if len(positional) > 2 {
+        fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+        printUsage()
+        return 1
     }
-
-    flagList := false
-    flagLast := false
-    var hostName string
+    hostName := positional[0]
     var sessionArg string
-
-    if args[0] == "--list" || args[0] == "--last" {
-        if len(args) != 2 {
-            fmt.Fprintln(os.Stderr, "Invalid arguments.")
-            printUsage()
-            return 1
-        }
-        flagList = args[0] == "--list"
-        flagLast = args[0] == "--last"
-        hostName = args[1]
-    } else {
-        hostName = args[0]
-        for _, arg := range args[1:] {
-            switch arg {
-            case "--list":
-                flagList = true
-            case "--last":
-                flagLast = true
-            default:
-                if sessionArg == "" {
-                    sessionArg = arg
-                } else {
-                    fmt.Fprintln(os.Stderr, "Too many positional arguments.")
-                    printUsage()
-                    return 1
-                }
-            }
-        }
+    if len(positional) == 2 {
+        sessionArg = positional[1]
     }
 
-    if flagList && flagLast {
+    mode := session.AttachDefault
+    modeFlags := 0
+    if opts.shared {
+        mode = session.AttachShared
+        modeFlags++
+    }
+    if opts.steal {
+        mode = session.AttachSteal
+        modeFlags++
+    }
+    if opts.detach {
+        mode = session.AttachPowerDetach
+        modeFlags++
+    }
+    if modeFlags > 1 {
+        fmt.Fprintln(os.Stderr, "--shared, --steal and --power-detach cannot be combined.")
+        return 1
+    }
+    if opts.list && modeFlags > 0 {
+        fmt.Fprintln(os.Stderr, "--list cannot be combined with attach options.")
+        return 1
+    }
+    if opts.list && len(opts.forwards) > 0 {
+        fmt.Fprintln(os.Stderr, "--list cannot be combined with --forward.")
+        return 1
+    }
+    if opts.list && opts.last {
         fmt.Fprintln(os.Stderr, "--list and --last cannot be combined.")
         return 1
     }
-    if flagList && sessionArg != "" {
+    if opts.list && sessionArg != "" {
         fmt.Fprintln(os.Stderr, "--list cannot be combined with a session name.")
         return 1
     }
-    if flagLast && sessionArg != "" {
+    if opts.last && sessionArg != "" {
         fmt.Fprintln(os.Stderr, "--last cannot be combined with a session name.")
         return 1
     }
@@ -79,117 +152,177 @@ func run(args []string) int {
     }
     host := config.FindHost(hosts, hostName)
     if host == nil {
-        fmt.Fprintf(os.Stderr, "Host '%s' is not configured. Use 'schh host add %s [target]'.\n", hostName, hostName)
-        return 1
+        return unknownHost(hostName, hosts)
+    }
+
+    for _, name := range opts.forwards {
+        if _, ok := host.Forwards[name]; !ok {
+            fmt.Fprintf(os.Stderr, "Host '%s' has no forward named '%s'.\n", host.Name, name)
+            return 1
+        }
     }
 
-    if flagList {
+    if opts.list {
         return listSessionsForHost(*host)
     }
 
-    if flagLast {
-        return attachLastSession(*host)
+    if opts.last {
+        return attachLastSession(*host, mode, opts.forwards)
     }
 
     if sessionArg != "" {
-        return runNamedSession(*host, sessionArg)
+        return runNamedSession(*host, sessionArg, mode, opts.forwards)
     }
 
-    return runInteractive(*host)
+    return runInteractive(*host, mode, opts.forwards)
 }
 
-func printUsage() {
-    fmt.Fprintf(os.Stderr, "Usage:\n")
-    fmt.Fprintf(os.Stderr, "  schh host add <name> [target]\n")
-    fmt.Fprintf(os.Stderr, "  schh host remove <name>\n")
-    fmt.Fprintf(os.Stderr, "  schh host list\n")
-    fmt.Fprintf(os.Stderr, "  schh <host-name> [session-name] [--list|--last]\n")
-    fmt.Fprintf(os.Stderr, "  schh --list <host-name>\n")
-    fmt.Fprintf(os.Stderr, "  schh --last <host-name>\n")
+func unknownHost(name string, hosts []config.Host) int {
+    if !strings.ContainsAny(name, ".@") {
+        if suggestion := suggest(name, commandNames(commands)); suggestion != "" {
+            fmt.Fprintf(os.Stderr, "Unknown command or host '%s'. Did you mean 'schh %s'?\n", name, suggestion)
+            return 1
+        }
+    }
+    fmt.Fprintf(os.Stderr, "Host '%s' is not configured. Use 'schh host add %s [target]'.\n", name, name)
+    names := make([]string, 0, len(hosts))
+    for _, h := range hosts {
+        names = append(names, h.Name)
+    }
+    if suggestion := suggest(name, names); suggestion != "" {
+        fmt.Fprintf(os.Stderr, "Did you mean '%s'?\n", suggestion)
+    }
+    return 1
 }
 
-func runHostCommand(args []string) int {
+func runHostAdd(args []string) int {
     if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Please provide the host name to add.")
         printUsage()
         return 1
     }
-
-    switch args[0] {
-    case "add":
-        if len(args) < 2 {
-            fmt.Fprintln(os.Stderr, "Please provide the host name to add.")
-            printUsage()
+    if len(args) > 2 {
+        fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+        printUsage()
+        return 1
+    }
+    name := args[0]
+    target := name
+    if len(args) == 2 {
+        target = args[1]
+    }
+    if containsWhitespace(name) || containsWhitespace(target) {
+        fmt.Fprintln(os.Stderr, "Host names and targets cannot contain spaces.")
+        return 1
+    }
+    if err := config.AddHost(name, target); err != nil {
+        if errors.Is(err, config.ErrHostExists) {
+            fmt.Fprintf(os.Stderr, "Host '%s' already exists.\n", name)
             return 1
         }
-        name := args[1]
-        target := name
-        if len(args) >= 3 {
-            target = args[2]
-        }
-        if containsWhitespace(name) || containsWhitespace(target) {
-            fmt.Fprintln(os.Stderr, "Host names and targets cannot contain spaces.")
+        if errors.Is(err, config.ErrReservedName) {
+            fmt.Fprintf(os.Stderr, "'%s' is a schh command; pick another host name.\n", name)
             return 1
         }
-        if err := config.AddHost(name, target); err != nil {
-            if errors.Is(err, config.ErrHostExists) {
-                fmt.Fprintf(os.Stderr, "Host '%s' already exists.\n", name)
-                return 1
-            }
-            fmt.Fprintf(os.Stderr, "Unable to save host '%s': %v\n", name, err)
+        if errors.Is(err, config.ErrInvalidTarget) {
+            fmt.Fprintf(os.Stderr, "Unable to save host '%s': %v.\n", name, err)
             return 1
         }
-        fmt.Printf("Host '%s' saved as '%s'.\n", target, name)
-        return 0
-    case "remove":
-        if len(args) < 2 {
-            fmt.Fprintln(os.Stderr, "Please provide the host name to remove.")
-            printUsage()
+        fmt.Fprintf(os.Stderr, "Unable to save host '%s': %v\n", name, err)
+        return 1
+    }
+    recordAudit(config.AuditHostAdd, config.Host{Name: name, Target: target}, "", "", "")
+    fmt.Printf("Host '%s' saved as '%s'.\n", target, name)
+    return 0
+}
+
+func runHostRemove(args []string) int {
+    if len(args) != 1 {
+        fmt.Fprintln(os.Stderr, "Please provide the host name to remove.")
+        printUsage()
+        return 1
+    }
+    name := args[0]
+    removed, err := config.RemoveHost(name)
+    if err != nil {
+        if errors.Is(err, config.ErrHostNotFound) {
+            fmt.Fprintf(os.Stderr, "Host '%s' was not found.\n", name)
             return 1
         }
-        name := args[1]
-        if err := config.RemoveHost(name); err != nil {
-            if errors.Is(err, config.ErrHostNotFound) {
-                fmt.Fprintf(os.Stderr, "Host '%s' was not found.\n", name)
-                return 1
-            }
-            fmt.Fprintf(os.Stderr, "Unable to remove host '%s': %v\n", name, err)
+        if errors.Is(err, config.ErrReadOnlySource) {
+            fmt.Fprintf(os.Stderr, "Host '%s' comes from a shared source and cannot be removed here (%v).\n", name, err)
             return 1
         }
-        cleared, err := config.ClearLastSessionLabel(name)
-        if err != nil {
-            fmt.Printf("Host '%s' removed (unable to clear recent sessions).\n", name)
-            return 0
+        fmt.Fprintf(os.Stderr, "Unable to remove host '%s': %v\n", name, err)
+        return 1
+    }
+    recordAudit(config.AuditHostRemove, removed, "", "", "")
+    cleared, err := config.ClearLastSessionLabel(name)
+    if err != nil {
+        fmt.Printf("Host '%s' removed (unable to clear recent sessions).\n", name)
+        return 0
+    }
+    if cleared {
+        fmt.Printf("Host '%s' removed and recent sessions cleared.\n", name)
+    } else {
+        fmt.Printf("Host '%s' removed.\n", name)
+    }
+    return 0
+}
+
+func setupHostList(fs *flag.FlagSet) func(args []string) int {
+    showSources := fs.Bool("sources", false, "show the file or inventory each host comes from")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
         }
-        if cleared {
-            fmt.Printf("Host '%s' removed and recent sessions cleared.\n", name)
-        } else {
-            fmt.Printf("Host '%s' removed.\n", name)
+        return runHostList(*showSources)
+    }
+}
+
+func runHostList(showSources bool) int {
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to load configured hosts: %v\n", err)
+        return 1
+    }
+    if globals.json {
+        if hosts == nil {
+            hosts = []config.Host{}
         }
+        return printJSON(hosts)
+    }
+    if len(hosts) == 0 {
+        fmt.Println("No hosts configured. Use 'schh host add <name> [target]'.")
         return 0
-    case "list":
-        hosts, err := config.LoadHosts()
-        if err != nil {
-            fmt.Fprintf(os.Stderr, "Unable to load configured hosts: %v\n", err)
-            return 1
+    }
+    fmt.Println("Configured hosts:")
+    for _, h := range hosts {
+        entry := h.Name
+        switch kind := config.HostKind(h); {
+        case kind == config.KindLocal:
+            entry = fmt.Sprintf("%s -> local", h.Name)
+        case kind != config.KindSSH:
+            entry = fmt.Sprintf("%s -> %s:%s", h.Name, kind, h.Target)
+        case h.Name != h.Target:
+            entry = fmt.Sprintf("%s -> %s", h.Name, h.Target)
         }
-        if len(hosts) == 0 {
-            fmt.Println("No hosts configured. Use 'schh host add <name> [target]'.")
-            return 0
+        if len(h.Tags) > 0 {
+            entry = fmt.Sprintf("%s  (%s)", entry, strings.Join(h.Tags, ", "))
         }
-        fmt.Println("Configured hosts:")
-        for _, h := range hosts {
-            if h.Name == h.Target {
-                fmt.Printf("  - %s\n", h.Name)
-            } else {
-                fmt.Printf("  - %s -> %s\n", h.Name, h.Target)
-            }
+        if showSources {
+            entry = fmt.Sprintf("%s  [%s]", entry, h.Source)
+        }
+        fmt.Printf("  - %s\n", entry)
+    }
+    for _, h := range hosts {
+        if config.IsReservedName(h.Name) {
+            fmt.Fprintf(os.Stderr, "Warning: host '%s' has the same name as a command; connect with 'schh connect %s'.\n", h.Name, h.Name)
         }
-        return 0
-    default:
-        fmt.Fprintln(os.Stderr, "Unknown host command.")
-        printUsage()
-        return 1
     }
+    return 0
 }
 
 func listSessionsForHost(host config.Host) int {
@@ -198,6 +331,12 @@ func listSessionsForHost(host config.Host) int {
         fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
         return 1
     }
+    if globals.json {
+        if sessions == nil {
+            sessions = []session.Info{}
+        }
+        return printJSON(sessions)
+    }
     lastLabel, err := config.GetLastSessionLabel(host.Name)
     hasLast := err == nil
 
@@ -216,7 +355,7 @@ func listSessionsForHost(host config.Host) int {
     return 0
 }
 
-func attachLastSession(host config.Host) int {
+func attachLastSession(host config.Host, mode session.AttachMode, forwards []string) int {
     lastLabel, err := config.GetLastSessionLabel(host.Name)
     if err != nil {
         if errors.Is(err, config.ErrLabelNotFound) {
@@ -238,13 +377,15 @@ func attachLastSession(host config.Host) int {
     }
     exists := false
     for _, s := range sessions {
-        if s.ID == sessionID {
+        if s.Name == sessionID {
             exists = true
+            mode = resolveAttachMode(s, mode)
+            warnForwardsIgnored(s.Label, forwards)
             break
         }
     }
     if !exists {
-        if err := session.StartDetachedSession(sessionID, host.Target); err != nil {
+        if err := startSession(host, sessionID, lastLabel, forwards); err != nil {
             fmt.Fprintf(os.Stderr, "Unable to start session '%s': %v\n", lastLabel, err)
             return 1
         }
@@ -252,14 +393,10 @@ func attachLastSession(host config.Host) int {
     if err := config.SetLastSessionLabel(host.Name, lastLabel); err != nil {
         fmt.Fprintf(os.Stderr, "Warning: unable to update recent sessions: %v\n", err)
     }
-    if err := session.AttachSession(sessionID); err != nil {
-        fmt.Fprintf(os.Stderr, "Unable to attach to session: %v\n", err)
-        return 1
-    }
-    return 0
+    return attachSession(host, sessionID, lastLabel, mode)
 }
 
-func runNamedSession(host config.Host, sessionArg string) int {
+func runNamedSession(host config.Host, sessionArg string, mode session.AttachMode, forwards []string) int {
     label := session.SanitizeToken(sessionArg)
     if label == "" {
         fmt.Fprintln(os.Stderr, "Invalid session name.")
@@ -277,13 +414,15 @@ func runNamedSession(host config.Host, sessionArg string) int {
     }
     exists := false
     for _, s := range sessions {
-        if s.ID == sessionID {
+        if s.Name == sessionID {
             exists = true
+            mode = resolveAttachMode(s, mode)
+            warnForwardsIgnored(s.Label, forwards)
             break
         }
     }
     if !exists {
-        if err := session.StartDetachedSession(sessionID, host.Target); err != nil {
+        if err := startSession(host, sessionID, label, forwards); err != nil {
             fmt.Fprintf(os.Stderr, "Unable to start session '%s': %v\n", label, err)
             return 1
         }
@@ -291,14 +430,10 @@ func runNamedSession(host config.Host, sessionArg string) int {
     if err := config.SetLastSessionLabel(host.Name, label); err != nil {
         fmt.Fprintf(os.Stderr, "Warning: unable to update recent sessions: %v\n", err)
     }
-    if err := session.AttachSession(sessionID); err != nil {
-        fmt.Fprintf(os.Stderr, "Unable to attach to session: %v\n", err)
-        return 1
-    }
-    return 0
+    return attachSession(host, sessionID, label, mode)
 }
 
-func runInteractive(host config.Host) int {
+func runInteractive(host config.Host, mode session.AttachMode, forwards []string) int {
     sessions, err := session.ListSessionsForHost(host.Name)
     if err != nil {
         fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
@@ -321,11 +456,11 @@ func runInteractive(host config.Host) int {
         if err := config.SetLastSessionLabel(host.Name, label); err != nil {
             fmt.Fprintf(os.Stderr, "Warning: unable to update recent sessions: %v\n", err)
         }
-        if err := session.AttachSession(choice.SessionID); err != nil {
-            fmt.Fprintf(os.Stderr, "Unable to attach to session: %v\n", err)
-            return 1
+        if choice.Mode != session.AttachDefault {
+            mode = choice.Mode
         }
-        return 0
+        warnForwardsIgnored(choice.Label, forwards)
+        return attachSession(host, choice.SessionID, label, mode)
     case ui.ActionCreate:
         label := session.SanitizeToken(choice.Label)
         if label == "" {
@@ -337,24 +472,124 @@ func runInteractive(host config.Host) int {
             fmt.Fprintf(os.Stderr, "Invalid session name: %v\n", err)
             return 1
         }
-        if err := session.StartDetachedSession(sessionID, host.Target); err != nil {
+        if err := startSession(host, sessionID, label, forwards); err != nil {
             fmt.Fprintf(os.Stderr, "Unable to start session '%s': %v\n", label, err)
             return 1
         }
         if err := config.SetLastSessionLabel(host.Name, label); err != nil {
             fmt.Fprintf(os.Stderr, "Warning: unable to update recent sessions: %v\n", err)
         }
-        if err := session.AttachSession(sessionID); err != nil {
-            fmt.Fprintf(os.Stderr, "Unable to attach to session: %v\n", err)
-            return 1
-        }
-        return 0
+        return attachSession(host, sessionID, label, session.AttachDefault)
     default:
         fmt.Fprintln(os.Stderr, "Unknown selection.")
         return 1
     }
 }
 
+func startSession(host config.Host, sessionID, label string, forwards []string) error {
+    opts := connect.Options{Forwards: forwards}
+    if len(host.Via) > 0 {
+        hosts, err := config.LoadHosts()
+        if err != nil {
+            return err
+        }
+        opts.Hosts = hosts
+    }
+    command, err := connect.Command(host, opts)
+    if err != nil {
+        return err
+    }
+    settings, err := config.LoadSettings()
+    if err != nil {
+        return err
+    }
+    hookCtx := hook.Context{Host: host.Name, Target: host.Target, Label: label, SessionID: sessionID}
+    if err := hook.Run(hook.PreStart, config.HookCommands(settings, host, hook.PreStart), hookCtx); err != nil {
+        return err
+    }
+    var screenrc string
+    if screen := connect.ScreenOptions(host); !screen.IsZero() {
+        screenrc, err = config.ScreenrcPath(sessionID)
+        if err != nil {
+            return err
+        }
+        if err := session.WriteScreenrc(screenrc, screen); err != nil {
+            return err
+        }
+    }
+    if err := session.StartDetachedSession(sessionID, command, screenrc); err != nil {
+        return err
+    }
+    recordAudit(config.AuditCreate, host, label, sessionID, strings.Join(forwards, ","))
+    record := config.SessionRecord{ID: sessionID, Host: host.Name, Label: label, Forwards: forwards, Created: time.Now()}
+    if err := config.SaveSessionRecord(record); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to record session: %v\n", err)
+    }
+    return nil
+}
+
+func attachSession(host config.Host, sessionID, label string, mode session.AttachMode) int {
+    settings, err := config.LoadSettings()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load settings: %v\n", err)
+        return 1
+    }
+    hookCtx := hook.Context{Host: host.Name, Target: host.Target, Label: label, SessionID: sessionID}
+    if err := hook.Run(hook.PreAttach, config.HookCommands(settings, host, hook.PreAttach), hookCtx); err != nil {
+        fmt.Fprintf(os.Stderr, "Not attaching: %v\n", err)
+        return 1
+    }
+
+    recordAudit(config.AuditAttach, host, label, sessionID, "")
+    record := config.SessionRecord{ID: sessionName(sessionID), Host: host.Name, Label: label, Created: time.Now()}
+    if err := config.TouchSessionRecord(record, time.Now()); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to record session: %v\n", err)
+    }
+    if err := session.AttachSessionWait(sessionID, mode); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to attach to session: %v\n", err)
+        return 1
+    }
+    recordAudit(config.AuditDetach, host, label, sessionID, "")
+    if err := config.TouchSessionRecord(record, time.Now()); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to record session: %v\n", err)
+    }
+    if err := hook.Run(hook.PostDetach, config.HookCommands(settings, host, hook.PostDetach), hookCtx); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+    }
+    return 0
+}
+
+func sessionName(sessionID string) string {
+    if _, name, ok := strings.Cut(sessionID, "."); ok {
+        return name
+    }
+    return sessionID
+}
+
+func warnForwardsIgnored(label string, forwards []string) {
+    if len(forwards) > 0 {
+        fmt.Fprintf(os.Stderr, "Forwards only apply to new sessions; '%s' keeps its existing tunnels.\n", label)
+    }
+}
+
+func resolveAttachMode(s session.Info, mode session.AttachMode) session.AttachMode {
+    if s.Attached && mode == session.AttachDefault {
+        fmt.Fprintf(os.Stderr, "Session '%s' is attached elsewhere; sharing it. Use --steal to detach the other display.\n", s.Label)
+        return session.AttachShared
+    }
+    return mode
+}
+
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
 func containsWhitespace(text string) bool {
     return strings.ContainsAny(text, " \t")
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/connect"
+)
+
+func runMaster(args []string) int {
+    if len(args) != 2 {
+        fmt.Fprintln(os.Stderr, "Please provide 'status' or 'stop' and a host name.")
+        printUsage()
+        return 1
+    }
+    var operation string
+    switch args[0] {
+    case "status":
+        operation = "check"
+    case "stop":
+        operation = "exit"
+    default:
+        fmt.Fprintln(os.Stderr, "Unknown master command.")
+        printUsage()
+        return 1
+    }
+
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load hosts: %v\n", err)
+        return 1
+    }
+    host := config.FindHost(hosts, args[1])
+    if host == nil {
+        fmt.Fprintf(os.Stderr, "Host '%s' is not configured. Use 'schh host add %s [target]'.\n", args[1], args[1])
+        return 1
+    }
+    if !host.Multiplex {
+        fmt.Fprintf(os.Stderr, "Connection sharing is not enabled for '%s'. Use 'schh host edit %s --set multiplex=on'.\n", host.Name, host.Name)
+        return 1
+    }
+    command, err := connect.ControlCommand(*host, operation, connect.Options{Hosts: hosts})
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to build the ssh command: %v\n", err)
+        return 1
+    }
+
+    output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+    message := strings.TrimSpace(string(output))
+    var exitErr *exec.ExitError
+    if err != nil && !errors.As(err, &exitErr) {
+        fmt.Fprintf(os.Stderr, "Unable to run ssh: %v\n", err)
+        return 1
+    }
+    if err != nil {
+        if operation == "check" {
+            fmt.Printf("No master connection for '%s'.\n", host.Name)
+        } else {
+            fmt.Fprintf(os.Stderr, "Unable to stop the master connection for '%s': %s\n", host.Name, message)
+        }
+        return 1
+    }
+    if operation == "check" {
+        fmt.Printf("Master connection for '%s' is running (%s).\n", host.Name, message)
+    } else {
+        fmt.Printf("Master connection for '%s' stopped.\n", host.Name)
+    }
+    return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+func setupMigrate(fs *flag.FlagSet) func(args []string) int {
+    dryRun := fs.Bool("dry-run", false, "show the renames without applying them")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runMigrate(*dryRun)
+    }
+}
+
+func runMigrate(dryRun bool) int {
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load hosts: %v\n", err)
+        return 1
+    }
+    sessions, err := session.ListAllSessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    names := make([]string, 0, len(hosts))
+    for _, h := range hosts {
+        names = append(names, h.Name)
+    }
+    planned, unresolved := session.PlanMigration(sessions, names)
+    if len(planned) == 0 && len(unresolved) == 0 {
+        fmt.Println("All sessions already use the current naming scheme.")
+        return 0
+    }
+
+    taken := make(map[string]bool)
+    for _, s := range sessions {
+        taken[s.Name] = true
+    }
+    status := 0
+    for _, m := range planned {
+        if taken[m.NewID] {
+            fmt.Fprintf(os.Stderr, "Skipping '%s': a session named '%s' already exists.\n", m.Session.Name, m.NewID)
+            status = 1
+            continue
+        }
+        if dryRun {
+            fmt.Printf("Would rename '%s' to '%s' (host %s, label %s).\n", m.Session.Name, m.NewID, m.HostName, m.Label)
+            continue
+        }
+        if err := session.RenameSession(m.Session.ID, m.NewID); err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to rename '%s': %v\n", m.Session.Name, err)
+            status = 1
+            continue
+        }
+        taken[m.NewID] = true
+        fmt.Printf("Renamed '%s' to '%s'.\n", m.Session.Name, m.NewID)
+    }
+    for _, s := range unresolved {
+        fmt.Fprintf(os.Stderr, "Cannot tell which host owns '%s'; rename it with 'screen -S %s -X sessionname schh_<host>_<label>'.\n", s.Name, s.ID)
+        status = 1
+    }
+    return status
+}
+
+func warnLegacySessions(sessions []session.Info) {
+    names := make([]string, 0, len(sessions))
+    for _, s := range sessions {
+        names = append(names, s.Name)
+    }
+    fmt.Fprintf(os.Stderr, "Warning: sessions named by an older schh release are hidden (%s); run 'schh migrate' to rename them.\n", strings.Join(names, ", "))
+}
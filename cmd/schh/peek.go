@@ -0,0 +1,86 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+const defaultPeekLines = 20
+
+func setupPeek(fs *flag.FlagSet) func(args []string) int {
+    lines := fs.Int("lines", defaultPeekLines, "number of `lines` to show")
+    fs.IntVar(lines, "n", defaultPeekLines, "number of `lines` to show")
+    return func(args []string) int {
+        if *lines < 1 {
+            fmt.Fprintf(os.Stderr, "Invalid number of lines '%d'.\n", *lines)
+            return 1
+        }
+        return runPeek(args, *lines)
+    }
+}
+
+func runPeek(positional []string, lines int) int {
+    if len(positional) != 2 {
+        fmt.Fprintln(os.Stderr, "Please provide a host name and a session name.")
+        printUsage()
+        return 1
+    }
+
+    host, code := loadHost(positional[0])
+    if host == nil {
+        return code
+    }
+    running, code := findRunningSession(*host, positional[1])
+    if running == nil {
+        return code
+    }
+    output, err := session.Hardcopy(running.ID, lines)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read session output: %v\n", err)
+        return 1
+    }
+    fmt.Println(strings.Join(output, "\n"))
+    return 0
+}
+
+func runSnapshot(args []string) int {
+    if len(args) != 2 {
+        fmt.Fprintln(os.Stderr, "Please provide a host name and a session name.")
+        printUsage()
+        return 1
+    }
+    host, code := loadHost(args[0])
+    if host == nil {
+        return code
+    }
+    running, code := findRunningSession(*host, args[1])
+    if running == nil {
+        return code
+    }
+    output, err := session.Hardcopy(running.ID, 0)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read session output: %v\n", err)
+        return 1
+    }
+
+    dir, err := config.SnapshotDir()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to create snapshot directory: %v\n", err)
+        return 1
+    }
+    name := fmt.Sprintf("%s_%s_%s.txt", session.SanitizeToken(host.Name), running.Label, time.Now().Format("20060102-150405"))
+    path := filepath.Join(dir, name)
+    if err := os.WriteFile(path, []byte(strings.Join(output, "\n")+"\n"), 0o600); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to save snapshot: %v\n", err)
+        return 1
+    }
+    fmt.Printf("Snapshot of '%s' saved to %s\n", running.Label, path)
+    return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "os/user"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+func setupShare(fs *flag.FlagSet) func(args []string) int {
+    var users listValue
+    fs.Var(&users, "user", "grant or revoke access for `user` (repeatable)")
+    readOnly := fs.Bool("readonly", false, "let the users watch without typing")
+    revoke := fs.Bool("revoke", false, "remove access instead of granting it")
+    return func(args []string) int {
+        return runShare(args, users, *readOnly, *revoke)
+    }
+}
+
+func runShare(positional, users []string, readOnly, revoke bool) int {
+    if len(positional) != 2 || len(users) == 0 {
+        fmt.Fprintln(os.Stderr, "Please provide a host, a session name and at least one --user.")
+        printUsage()
+        return 1
+    }
+    if revoke && readOnly {
+        fmt.Fprintln(os.Stderr, "--revoke cannot be combined with --readonly.")
+        return 1
+    }
+
+    host, code := loadHost(positional[0])
+    if host == nil {
+        return code
+    }
+    running, code := findRunningSession(*host, positional[1])
+    if running == nil {
+        return code
+    }
+
+    for _, u := range users {
+        var err error
+        if revoke {
+            err = session.UnshareSession(running.ID, u)
+        } else {
+            err = session.ShareSession(running.ID, u, readOnly)
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Unable to update access for '%s': %v\n", u, err)
+            return 1
+        }
+        switch {
+        case revoke:
+            fmt.Printf("Access for '%s' revoked.\n", u)
+        case readOnly:
+            fmt.Printf("'%s' can now watch the session (read-only).\n", u)
+        default:
+            fmt.Printf("'%s' can now join the session.\n", u)
+        }
+    }
+    if !revoke {
+        owner := "<owner>"
+        if current, err := user.Current(); err == nil {
+            owner = current.Username
+        }
+        fmt.Printf("Join with: schh join %s/%s/%s\n", owner, host.Name, running.Label)
+    }
+    return 0
+}
+
+func runJoin(args []string) int {
+    if len(args) != 1 {
+        fmt.Fprintln(os.Stderr, "Please provide the session as <owner>/<host>/<label>.")
+        printUsage()
+        return 1
+    }
+    parts := strings.Split(args[0], "/")
+    if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+        fmt.Fprintln(os.Stderr, "Please provide the session as <owner>/<host>/<label>.")
+        return 1
+    }
+    sessionID, err := session.BuildSessionID(parts[1], parts[2])
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Invalid session name: %v\n", err)
+        return 1
+    }
+    if err := session.JoinSession(parts[0], sessionID); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to join session: %v\n", err)
+        return 1
+    }
+    return 0
+}
+
+func loadHost(name string) (*config.Host, int) {
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load hosts: %v\n", err)
+        return nil, 1
+    }
+    host := config.FindHost(hosts, name)
+    if host == nil {
+        fmt.Fprintf(os.Stderr, "Host '%s' is not configured. Use 'schh host add %s [target]'.\n", name, name)
+        return nil, 1
+    }
+    return host, 0
+}
+
+func findRunningSession(host config.Host, label string) (*session.Info, int) {
+    sessionID, err := session.BuildSessionID(host.Name, label)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Invalid session name: %v\n", err)
+        return nil, 1
+    }
+    sessions, err := session.ListSessionsForHost(host.Name)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return nil, 1
+    }
+    for i := range sessions {
+        if sessions[i].Name == sessionID {
+            return &sessions[i], 0
+        }
+    }
+    fmt.Fprintf(os.Stderr, "Session '%s' is not running on '%s'.\n", session.SanitizeToken(label), host.Name)
+    return nil, 1
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+const (
+    exitSessionExists = 3
+    exitUnknownHost   = 4
+    exitBackendFailed = 5
+)
+
+const autoLabelAttempts = 20
+
+type startOptions struct {
+    detached     bool
+    ifMissing    bool
+    failIfExists bool
+    autoLabel    bool
+    forwards     []string
+}
+
+func setupStart(fs *flag.FlagSet) func(args []string) int {
+    var opts startOptions
+    var forwards listValue
+    fs.BoolVar(&opts.detached, "detached", false, "start the session without attaching and print its id")
+    fs.BoolVar(&opts.detached, "d", false, "start the session without attaching and print its id")
+    fs.BoolVar(&opts.ifMissing, "if-missing", false, "with --detached, succeed when the session already exists")
+    fs.BoolVar(&opts.failIfExists, "fail-if-exists", false, "exit with status 3 when the session already exists")
+    fs.BoolVar(&opts.autoLabel, "auto-label", false, "generate an unused session name")
+    fs.Var(&forwards, "forward", "open the forward profile `name` (repeatable)")
+    return func(args []string) int {
+        opts.forwards = forwards
+        return runStart(args, opts)
+    }
+}
+
+func runStart(positional []string, opts startOptions) int {
+    switch {
+    case len(positional) == 0 || len(positional) > 2:
+        fmt.Fprintln(os.Stderr, "Please provide a host name and an optional session name.")
+        printUsage()
+        return 1
+    case opts.ifMissing && opts.failIfExists:
+        fmt.Fprintln(os.Stderr, "--if-missing cannot be combined with --fail-if-exists.")
+        return 1
+    case opts.autoLabel && len(positional) == 2:
+        fmt.Fprintln(os.Stderr, "--auto-label cannot be combined with a session name.")
+        return 1
+    case !opts.autoLabel && len(positional) == 1:
+        fmt.Fprintln(os.Stderr, "Please provide a session name or --auto-label.")
+        return 1
+    }
+
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Failed to load hosts: %v\n", err)
+        return 1
+    }
+    host := config.FindHost(hosts, positional[0])
+    if host == nil {
+        fmt.Fprintf(os.Stderr, "Host '%s' is not configured.\n", positional[0])
+        return exitUnknownHost
+    }
+    for _, name := range opts.forwards {
+        if _, ok := host.Forwards[name]; !ok {
+            fmt.Fprintf(os.Stderr, "Host '%s' has no forward named '%s'.\n", host.Name, name)
+            return 1
+        }
+    }
+
+    sessions, err := session.ScanSessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return exitBackendFailed
+    }
+    running := make(map[string]session.Info, len(sessions))
+    for _, s := range sessions {
+        running[s.Name] = s
+    }
+
+    var label, sessionID string
+    if opts.autoLabel {
+        for i := 0; i < autoLabelAttempts && sessionID == ""; i++ {
+            candidate := session.GenerateSessionLabel()
+            id, err := session.BuildSessionID(host.Name, candidate)
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "Invalid session name: %v\n", err)
+                return 1
+            }
+            if _, taken := running[id]; !taken {
+                label, sessionID = candidate, id
+            }
+        }
+        if sessionID == "" {
+            fmt.Fprintln(os.Stderr, "Unable to find an unused session name.")
+            return exitSessionExists
+        }
+    } else {
+        label = session.SanitizeToken(positional[1])
+        sessionID, err = session.BuildSessionID(host.Name, positional[1])
+        if label == "" || err != nil {
+            fmt.Fprintln(os.Stderr, "Invalid session name.")
+            return 1
+        }
+    }
+
+    existing, exists := running[sessionID]
+    switch {
+    case exists && (opts.failIfExists || (opts.detached && !opts.ifMissing)):
+        fmt.Fprintf(os.Stderr, "Session '%s' already exists on '%s'.\n", label, host.Name)
+        return exitSessionExists
+    case exists && opts.detached:
+        fmt.Println(sessionID)
+        return 0
+    case exists:
+        warnForwardsIgnored(label, opts.forwards)
+        return attachSession(*host, sessionID, label, resolveAttachMode(existing, session.AttachDefault))
+    }
+
+    if err := startSession(*host, sessionID, label, opts.forwards); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to start session '%s': %v\n", label, err)
+        return exitBackendFailed
+    }
+    if err := config.SetLastSessionLabel(host.Name, label); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: unable to update recent sessions: %v\n", err)
+    }
+    if opts.detached {
+        fmt.Println(sessionID)
+        return 0
+    }
+    return attachSession(*host, sessionID, label, session.AttachDefault)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+const defaultStatusMaxAge = 5 * time.Second
+
+type hostCount struct {
+    name     string
+    total    int
+    detached int
+}
+
+type hostStatus struct {
+    Host     string `json:"host"`
+    Sessions int    `json:"sessions"`
+    Detached int    `json:"detached"`
+}
+
+func setupStatus(fs *flag.FlagSet) func(args []string) int {
+    short := fs.Bool("short", false, "print a compact line for shell prompts")
+    maxAge := durationValue(defaultStatusMaxAge)
+    fs.Var(&maxAge, "max-age", "reuse a cached --short line younger than `duration` (default 5s)")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runStatus(*short, time.Duration(maxAge))
+    }
+}
+
+func runStatus(short bool, maxAge time.Duration) int {
+    if short && globals.json {
+        fmt.Fprintln(os.Stderr, "--short cannot be combined with --json.")
+        return 1
+    }
+    if short {
+        if cached, ok := config.ReadStatusCache(maxAge); ok {
+            fmt.Print(cached)
+            return 0
+        }
+        config.SetWarnings(io.Discard)
+        session.OnLegacySessions(nil)
+    }
+    sessions, err := session.ListAllSessions()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    counts := countSessions(sessions, hostNames())
+
+    line := formatShortStatus(counts)
+    if err := config.WriteStatusCache(line); err != nil && short {
+        fmt.Fprintf(os.Stderr, "Warning: unable to cache status: %v\n", err)
+    }
+    if short {
+        fmt.Print(line)
+        return 0
+    }
+    if globals.json {
+        statuses := make([]hostStatus, 0, len(counts))
+        for _, c := range counts {
+            statuses = append(statuses, hostStatus{Host: c.name, Sessions: c.total, Detached: c.detached})
+        }
+        return printJSON(statuses)
+    }
+    if len(counts) == 0 {
+        fmt.Println("No schh sessions running.")
+        return 0
+    }
+    fmt.Println("Running sessions:")
+    for _, c := range counts {
+        fmt.Printf("  %-20s %d session(s), %d detached\n", c.name, c.total, c.detached)
+    }
+    return 0
+}
+
+func countSessions(sessions []session.Info, names map[string]string) []hostCount {
+    byToken := make(map[string]*hostCount)
+    for _, s := range sessions {
+        if s.HostToken == "" {
+            continue
+        }
+        c, ok := byToken[s.HostToken]
+        if !ok {
+            c = &hostCount{name: s.HostToken}
+            if name, ok := names[s.HostToken]; ok {
+                c.name = name
+            }
+            byToken[s.HostToken] = c
+        }
+        c.total++
+        if !s.Attached {
+            c.detached++
+        }
+    }
+    counts := make([]hostCount, 0, len(byToken))
+    for _, c := range byToken {
+        counts = append(counts, *c)
+    }
+    sort.Slice(counts, func(i, j int) bool { return counts[i].name < counts[j].name })
+    return counts
+}
+
+func formatShortStatus(counts []hostCount) string {
+    if len(counts) == 0 {
+        return ""
+    }
+    parts := make([]string, 0, len(counts))
+    for _, c := range counts {
+        part := fmt.Sprintf("%s:%d", c.name, c.total)
+        if c.detached > 0 {
+            part += fmt.Sprintf("(%dd)", c.detached)
+        }
+        parts = append(parts, part)
+    }
+    return strings.Join(parts, " ") + "\n"
+}
+
+func setupCurrent(fs *flag.FlagSet) func(args []string) int {
+    host := fs.Bool("host", false, "print only the host name")
+    label := fs.Bool("label", false, "print only the session name")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        if *host && *label {
+            fmt.Fprintln(os.Stderr, "--host cannot be combined with --label.")
+            return 1
+        }
+        part := ""
+        if *host {
+            part = "host"
+        } else if *label {
+            part = "label"
+        }
+        return runCurrent(part)
+    }
+}
+
+func runCurrent(part string) int {
+    hostToken, label, ok := session.Current()
+    if !ok {
+        fmt.Fprintln(os.Stderr, "Not inside a schh session.")
+        return 1
+    }
+    hostName, ok := hostNames()[hostToken]
+    if !ok {
+        hostName = hostToken
+    }
+    switch {
+    case globals.json:
+        return printJSON(map[string]string{"host": hostName, "label": label})
+    case part == "host":
+        fmt.Println(hostName)
+    case part == "label":
+        fmt.Println(label)
+    default:
+        fmt.Printf("%s %s\n", hostName, label)
+    }
+    return 0
+}
+
+func hostNames() map[string]string {
+    names := make(map[string]string)
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        return names
+    }
+    for _, h := range hosts {
+        token := session.SanitizeToken(h.Name)
+        if _, taken := names[token]; !taken {
+            names[token] = h.Name
+        }
+    }
+    return names
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/upgrade"
+    "schh/internal/version"
+)
+
+const (
+    exitUpdateAvailable = 2
+    upgradeTimeout      = 5 * time.Minute
+)
+
+type upgradeStatus struct {
+    Current         string `json:"current"`
+    Latest          string `json:"latest"`
+    UpdateAvailable bool   `json:"update_available"`
+    Platform        string `json:"platform"`
+    URL             string `json:"url"`
+}
+
+func setupUpgrade(fs *flag.FlagSet) func(args []string) int {
+    check := fs.Bool("check", false, "only report whether a newer release exists")
+    feed := fs.String("feed", "", "read the release manifest from `url or path`")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        return runUpgrade(*feed, *check)
+    }
+}
+
+func runUpgrade(feed string, check bool) int {
+    if feed == "" {
+        settings, err := config.LoadSettings()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Failed to load settings: %v\n", err)
+            return 1
+        }
+        feed = settings.UpgradeFeed
+    }
+    if feed == "" {
+        fmt.Fprintln(os.Stderr, "No release feed configured. Add 'upgrade-feed <url>' to the settings file or pass --feed.")
+        return 1
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    ctx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+    defer cancel()
+
+    manifest, err := upgrade.LoadManifest(ctx, feed)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read the release feed: %v\n", err)
+        return 1
+    }
+    release, asset, err := manifest.Latest(upgrade.Platform())
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to find a release: %v\n", err)
+        return 1
+    }
+    current := version.Get().Version
+    status := upgradeStatus{
+        Current:         current,
+        Latest:          release.Version,
+        UpdateAvailable: upgrade.Compare(release.Version, current) > 0,
+        Platform:        upgrade.Platform(),
+        URL:             asset.URL,
+    }
+
+    if check {
+        code := 0
+        if status.UpdateAvailable {
+            code = exitUpdateAvailable
+        }
+        if globals.json {
+            printJSON(status)
+            return code
+        }
+        if status.UpdateAvailable {
+            fmt.Printf("schh %s is available (running %s).\n", release.Version, current)
+        } else {
+            fmt.Printf("schh %s is up to date.\n", current)
+        }
+        return code
+    }
+    if !status.UpdateAvailable {
+        fmt.Printf("schh %s is up to date.\n", current)
+        return 0
+    }
+
+    executable, err := os.Executable()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to locate the running binary: %v\n", err)
+        return 1
+    }
+    data, err := upgrade.Download(ctx, feed, asset)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to download schh %s: %v\n", release.Version, err)
+        return 1
+    }
+    if err := upgrade.Replace(executable, data); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to replace %s: %v\n", executable, err)
+        return 1
+    }
+    fmt.Printf("Updated schh from %s to %s.\n", current, release.Version)
+    return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+    "schh/internal/version"
+)
+
+const toolTimeout = 2 * time.Second
+
+type toolVersion struct {
+    Name    string `json:"name"`
+    Version string `json:"version,omitempty"`
+    Error   string `json:"error,omitempty"`
+}
+
+type versionReport struct {
+    version.Info
+    Backend string        `json:"backend"`
+    Tools   []toolVersion `json:"tools"`
+    Paths   config.Paths  `json:"paths"`
+}
+
+func runVersion(args []string) int {
+    if len(args) > 0 {
+        fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+        printUsage()
+        return 1
+    }
+    paths, err := config.ResolvePaths()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to resolve config paths: %v\n", err)
+        return 1
+    }
+    report := versionReport{
+        Info:    version.Get(),
+        Backend: session.BackendName(),
+        Tools: []toolVersion{
+            detectTool("screen", "-v"),
+            detectTool("tmux", "-V"),
+            detectTool("ssh", "-V"),
+        },
+        Paths: paths,
+    }
+    if globals.json {
+        return printJSON(report)
+    }
+
+    fmt.Println(report.Info)
+    fmt.Printf("%s %s, %s backend\n", report.GoVersion, report.Platform, report.Backend)
+    fmt.Println("\nTools:")
+    for _, tool := range report.Tools {
+        detail := tool.Version
+        if tool.Error != "" {
+            detail = "(" + tool.Error + ")"
+        }
+        fmt.Printf("  %-8s %s\n", tool.Name, detail)
+    }
+    fmt.Println("\nPaths:")
+    fmt.Printf("  %-9s %s\n", "config", describePath(paths.ConfigDir))
+    fmt.Printf("  %-9s %s\n", "hosts", describePath(paths.Hosts))
+    fmt.Printf("  %-9s %s\n", "settings", describePath(paths.Settings))
+    fmt.Printf("  %-9s %s\n", "system", describePath(paths.SystemDir))
+    fmt.Printf("  %-9s %s\n", "state", describePath(paths.StateDir))
+    return 0
+}
+
+func detectTool(name string, args ...string) toolVersion {
+    tool := toolVersion{Name: name}
+    path, err := exec.LookPath(name)
+    if err != nil {
+        tool.Error = "not found"
+        return tool
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), toolTimeout)
+    defer cancel()
+    output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+    line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+    var exitErr *exec.ExitError
+    if line == "" || (err != nil && !errors.As(err, &exitErr)) {
+        tool.Error = "unable to read version"
+        return tool
+    }
+    tool.Version = strings.TrimSpace(line)
+    return tool
+}
+
+func describePath(path string) string {
+    if _, err := os.Stat(path); err != nil {
+        return path + " (missing)"
+    }
+    return path
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/daemon"
+    "schh/internal/session"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+func setupWatch(fs *flag.FlagSet) func(args []string) int {
+    interval := durationValue(defaultWatchInterval)
+    fs.Var(&interval, "interval", "polling `duration` when no daemon is running (default 2s)")
+    return func(args []string) int {
+        if len(args) > 0 {
+            fmt.Fprintln(os.Stderr, "Too many positional arguments.")
+            printUsage()
+            return 1
+        }
+        if interval <= 0 {
+            fmt.Fprintf(os.Stderr, "Invalid interval '%s'.\n", interval.String())
+            return 1
+        }
+        return runWatch(time.Duration(interval), globals.json)
+    }
+}
+
+func runWatch(interval time.Duration, asJSON bool) int {
+    hostNames := make(map[string]string)
+    if hosts, err := config.LoadHosts(); err == nil {
+        for _, h := range hosts {
+            hostNames[session.SanitizeToken(h.Name)] = h.Name
+        }
+    }
+    encoder := json.NewEncoder(os.Stdout)
+    emit := func(event session.Event) {
+        event.Host = hostNames[event.Session.HostToken]
+        if asJSON {
+            encoder.Encode(event)
+            return
+        }
+        name := event.Session.Name
+        if event.Host != "" {
+            name = event.Host + "/" + event.Session.Label
+        }
+        fmt.Printf("%s  %-8s  %s\n", event.Time.Local().Format("15:04:05"), event.Type, name)
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    if os.Getenv("SCHH_NO_DAEMON") == "" {
+        if client, err := daemon.Connect(); err == nil && sameBackend(client) {
+            if err := client.Events(ctx, emit); err == nil || ctx.Err() != nil {
+                return 0
+            }
+            fmt.Fprintln(os.Stderr, "Lost the connection to the schh daemon; polling the backend instead.")
+        }
+    }
+    if err := pollSessions(ctx, interval, emit); err != nil {
+        fmt.Fprintf(os.Stderr, "Unable to read active sessions: %v\n", err)
+        return 1
+    }
+    return 0
+}
+
+func pollSessions(ctx context.Context, interval time.Duration, emit func(session.Event)) error {
+    prev, err := session.ScanSessions()
+    if err != nil {
+        return err
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    failing := false
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+        }
+        next, err := session.ScanSessions()
+        if err != nil {
+            if !failing {
+                fmt.Fprintf(os.Stderr, "Unable to read active sessions, retrying: %v\n", err)
+            }
+            failing = true
+            continue
+        }
+        failing = false
+        for _, event := range session.DiffSessions(prev, next, time.Now()) {
+            emit(event)
+        }
+        prev = next
+    }
+}
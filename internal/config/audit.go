@@ -0,0 +1,160 @@
+package config
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "os"
+    "os/user"
+    "path/filepath"
+    "time"
+)
+
+const (
+    AuditCreate     = "create"
+    AuditAttach     = "attach"
+    AuditDetach     = "detach"
+    AuditKill       = "kill"
+    AuditHostAdd    = "host-add"
+    AuditHostRemove = "host-remove"
+    AuditHostEdit   = "host-edit"
+    AuditHostRename = "host-rename"
+)
+
+type AuditEntry struct {
+    Time      time.Time `json:"time"`
+    User      string    `json:"user"`
+    Event     string    `json:"event"`
+    Host      string    `json:"host"`
+    Target    string    `json:"target,omitempty"`
+    Label     string    `json:"label,omitempty"`
+    SessionID string    `json:"session_id,omitempty"`
+    Detail    string    `json:"detail,omitempty"`
+}
+
+func auditFilePath() (string, error) {
+    return stateFilePath("audit.log")
+}
+
+func AppendAudit(entry AuditEntry) error {
+    if entry.Time.IsZero() {
+        entry.Time = time.Now()
+    }
+    entry.Time = entry.Time.UTC()
+    if entry.User == "" {
+        entry.User = currentUser()
+    }
+    line, err := json.Marshal(entry)
+    if err != nil {
+        return err
+    }
+    path, err := auditFilePath()
+    if err != nil {
+        return err
+    }
+    unlock, err := lockFile(path)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+    if err != nil {
+        return err
+    }
+    if _, err := file.Write(append(line, '\n')); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+func PruneAuditRetention() error {
+    settings, err := LoadSettings()
+    if err != nil || settings.AuditRetention <= 0 {
+        return err
+    }
+    return PruneAudit(time.Now().Add(-settings.AuditRetention))
+}
+
+func LoadAudit() ([]AuditEntry, error) {
+    path, err := auditFilePath()
+    if err != nil {
+        return nil, err
+    }
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return []AuditEntry{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    entries := []AuditEntry{}
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        var entry AuditEntry
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+func PruneAudit(cutoff time.Time) error {
+    path, err := auditFilePath()
+    if err != nil {
+        return err
+    }
+    unlock, err := lockFile(path)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+    entries, err := LoadAudit()
+    if err != nil {
+        return err
+    }
+    if len(entries) == 0 || !entries[0].Time.Before(cutoff) {
+        return nil
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".audit-*")
+    if err != nil {
+        return err
+    }
+    writer := bufio.NewWriter(tmp)
+    for _, entry := range entries {
+        if entry.Time.Before(cutoff) {
+            continue
+        }
+        line, err := json.Marshal(entry)
+        if err != nil {
+            tmp.Close()
+            os.Remove(tmp.Name())
+            return err
+        }
+        writer.Write(append(line, '\n'))
+    }
+    if err := writer.Flush(); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        os.Remove(tmp.Name())
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func currentUser() string {
+    if u, err := user.Current(); err == nil && u.Username != "" {
+        return u.Username
+    }
+    return os.Getenv("USER")
+}
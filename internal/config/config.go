@@ -7,20 +7,99 @@ import (
     "os"
     "path/filepath"
     "strings"
+
+    "schh/internal/session"
 )
 
 type Host struct {
-    Name   string
-    Target string
+    Name     string            `json:"name"`
+    Target   string            `json:"target,omitempty"`
+    Tags     []string          `json:"tags,omitempty"`
+    Options  map[string]string `json:"options,omitempty"`
+    Forwards map[string]string `json:"forwards,omitempty"`
+    Via      []string          `json:"via,omitempty"`
+
+    Kind      string `json:"kind,omitempty"`
+    Shell     string `json:"shell,omitempty"`
+    Container string `json:"container,omitempty"`
+    Pod       string `json:"pod,omitempty"`
+    Namespace string `json:"namespace,omitempty"`
+    Context   string `json:"context,omitempty"`
+
+    Multiplex      bool   `json:"multiplex,omitempty"`
+    ControlPersist string `json:"control_persist,omitempty"`
+
+    IdleTimeout string `json:"idle_timeout,omitempty"`
+    MaxAge      string `json:"max_age,omitempty"`
+
+    Env     map[string]string `json:"env,omitempty"`
+    SendEnv []string          `json:"send_env,omitempty"`
+    Term    string            `json:"term,omitempty"`
+    Screen  map[string]string `json:"screen,omitempty"`
+
+    Hooks map[string]string `json:"hooks,omitempty"`
+
+    Source string `json:"-"`
 }
 
+const (
+    KindSSH     = "ssh"
+    KindLocal   = "local"
+    KindDocker  = "docker"
+    KindKubectl = "kubectl"
+)
+
+var Kinds = []string{KindSSH, KindLocal, KindDocker, KindKubectl}
+
 var (
-    ErrHostExists    = errors.New("host already exists")
-    ErrHostNotFound  = errors.New("host not found")
-    ErrLabelNotFound = errors.New("no saved session label")
+    ErrHostExists      = errors.New("host already exists")
+    ErrHostNotFound    = errors.New("host not found")
+    ErrLabelNotFound   = errors.New("no saved session label")
+    ErrReadOnlySource  = errors.New("host is defined in a read-only source")
+    ErrHostCollision   = errors.New("host name collides with an existing host")
+    ErrInvalidHostName = errors.New("invalid host name")
+    ErrReservedName    = errors.New("host name is reserved for a command")
+    ErrInvalidTarget   = errors.New("invalid target")
 )
 
-func ensureConfigDir() (string, error) {
+var (
+    configDirOverride string
+    reservedNames     = make(map[string]bool)
+)
+
+func ReserveHostNames(names ...string) {
+    for _, name := range names {
+        reservedNames[name] = true
+    }
+}
+
+func IsReservedName(name string) bool {
+    return reservedNames[name]
+}
+
+func SetConfigDir(dir string) {
+    configDirOverride = dir
+}
+
+func Dir() (string, error) {
+    return ensureConfigDir()
+}
+
+func StateDir() (string, error) {
+    return ensureStateDir()
+}
+
+func resolveConfigDir() (string, error) {
+    if configDirOverride != "" {
+        return configDirOverride, nil
+    }
+    if dir := os.Getenv("SCHH_CONFIG_DIR"); dir != "" {
+        return dir, nil
+    }
+    return defaultConfigDir()
+}
+
+func defaultConfigDir() (string, error) {
     base, err := os.UserConfigDir()
     if err != nil || base == "" {
         home, homeErr := os.UserHomeDir()
@@ -32,7 +111,39 @@ func ensureConfigDir() (string, error) {
         }
         base = filepath.Join(home, ".config")
     }
-    dir := filepath.Join(base, "schh")
+    return filepath.Join(base, "schh"), nil
+}
+
+func resolveStateDir() (string, error) {
+    if dir := os.Getenv("SCHH_STATE_DIR"); dir != "" {
+        return dir, nil
+    }
+    if base := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(base) {
+        return filepath.Join(base, "schh"), nil
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".local", "state", "schh"), nil
+}
+
+func ensureConfigDir() (string, error) {
+    dir, err := resolveConfigDir()
+    if err != nil {
+        return "", err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return "", err
+    }
+    return dir, nil
+}
+
+func ensureStateDir() (string, error) {
+    dir, err := resolveStateDir()
+    if err != nil {
+        return "", err
+    }
     if err := os.MkdirAll(dir, 0o755); err != nil {
         return "", err
     }
@@ -48,50 +159,89 @@ func hostsFilePath() (string, error) {
 }
 
 func lastSessionsFilePath() (string, error) {
-    dir, err := ensureConfigDir()
+    return stateFilePath("last_sessions")
+}
+
+func stateFilePath(name string) (string, error) {
+    dir, err := ensureStateDir()
     if err != nil {
         return "", err
     }
-    return filepath.Join(dir, "last_sessions"), nil
+    path := filepath.Join(dir, name)
+    if err := migrateLegacyStateFile(path, name); err != nil {
+        return "", fmt.Errorf("migrate %s: %w", name, err)
+    }
+    return path, nil
 }
 
-func LoadHosts() ([]Host, error) {
-    path, err := hostsFilePath()
+func migrateLegacyStateFile(path, name string) error {
+    if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    configDir, err := defaultConfigDir()
     if err != nil {
-        return nil, err
+        return nil
     }
-
-    file, err := os.Open(path)
-    if errors.Is(err, os.ErrNotExist) {
-        return []Host{}, nil
+    legacy := filepath.Join(configDir, name)
+    if legacy == path {
+        return nil
+    }
+    if _, err := os.Stat(legacy); err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return nil
+        }
+        return err
+    }
+    if err := os.Rename(legacy, path); err == nil {
+        return nil
     }
+    data, err := os.ReadFile(legacy)
+    if err != nil {
+        return err
+    }
+    if err := os.WriteFile(path, data, 0o644); err != nil {
+        return err
+    }
+    return os.Remove(legacy)
+}
+
+func LoadHosts() ([]Host, error) {
+    return loadHosts(nil)
+}
+
+func loadHosts(personal []Host) ([]Host, error) {
+    sources, err := HostSources()
     if err != nil {
         return nil, err
     }
-    defer file.Close()
 
-    scanner := bufio.NewScanner(file)
-    var hosts []Host
-    for scanner.Scan() {
-        line := strings.TrimSpace(scanner.Text())
-        if line == "" || strings.HasPrefix(line, "#") {
-            continue
-        }
-        fields := strings.Fields(line)
-        if len(fields) == 0 {
-            continue
+    hosts := []Host{}
+    seen := make(map[string]bool)
+    for i, src := range sources {
+        loaded := personal
+        if i > 0 || personal == nil {
+            loaded, err = loadSource(src)
+            if err != nil && i == 0 {
+                return nil, err
+            }
+            if err != nil {
+                warnf("skipping host source %s: %v", src.Path, err)
+                continue
+            }
         }
-        name := fields[0]
-        target := name
-        if len(fields) > 1 {
-            target = fields[1]
+        for _, h := range loaded {
+            if seen[h.Name] {
+                continue
+            }
+            seen[h.Name] = true
+            hosts = append(hosts, h)
         }
-        hosts = append(hosts, Host{Name: name, Target: target})
     }
-    if err := scanner.Err(); err != nil {
-        return nil, err
+    strict := make(map[string]bool, len(personal))
+    for _, h := range personal {
+        strict[h.Name] = true
     }
-    return hosts, nil
+    return validateHosts(hosts, strict)
 }
 
 func FindHost(hosts []Host, name string) *Host {
@@ -104,16 +254,37 @@ func FindHost(hosts []Host, name string) *Host {
 }
 
 func AddHost(name, target string) error {
+    return AddHosts([]Host{{Name: name, Target: target}})
+}
+
+func AddHosts(hosts []Host) error {
     path, err := hostsFilePath()
     if err != nil {
         return err
     }
-    hosts, err := LoadHosts()
+    personal, err := readHostsFile(path)
     if err != nil {
         return err
     }
-    if FindHost(hosts, name) != nil {
-        return ErrHostExists
+    merged, err := LoadHosts()
+    if err != nil {
+        return err
+    }
+    for _, h := range hosts {
+        if FindHost(personal, h.Name) != nil {
+            return fmt.Errorf("%w: %s", ErrHostExists, h.Name)
+        }
+        if err := CheckNewHost(merged, h.Name); err != nil {
+            return err
+        }
+        if err := ValidateTarget(h.Target); err != nil {
+            return err
+        }
+        personal = append(personal, h)
+        merged = append(merged, h)
+    }
+    if _, err := loadHosts(personal); err != nil {
+        return err
     }
 
     file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
@@ -122,35 +293,197 @@ func AddHost(name, target string) error {
     }
     defer file.Close()
 
-    if _, err := fmt.Fprintf(file, "%s %s\n", name, target); err != nil {
+    writer := bufio.NewWriter(file)
+    for _, h := range hosts {
+        if _, err := fmt.Fprintln(writer, formatHostLine(h)); err != nil {
+            return err
+        }
+    }
+    return writer.Flush()
+}
+
+func RemoveHost(name string) (Host, error) {
+    path, index, hosts, err := personalHost(name)
+    if err != nil {
+        return Host{}, err
+    }
+    removed := hosts[index]
+    hosts = append(hosts[:index], hosts[index+1:]...)
+    if _, err := loadHosts(hosts); err != nil {
+        return Host{}, err
+    }
+    return removed, writeHostsFile(path, hosts)
+}
+
+func ValidateHostName(name string) error {
+    for _, r := range name {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+        default:
+            return fmt.Errorf("%w: %q contains %q", ErrInvalidHostName, name, r)
+        }
+    }
+    if session.SanitizeToken(name) == "" {
+        return fmt.Errorf("%w: %q", ErrInvalidHostName, name)
+    }
+    return nil
+}
+
+func ValidateTarget(target string) error {
+    switch {
+    case target == "", strings.ContainsAny(target, " \t"):
+        return fmt.Errorf("%w %q", ErrInvalidTarget, target)
+    case strings.HasPrefix(target, "-"):
+        return fmt.Errorf("%w %q: targets cannot start with '-'", ErrInvalidTarget, target)
+    }
+    return nil
+}
+
+func CheckNewHost(hosts []Host, name string) error {
+    if err := ValidateHostName(name); err != nil {
         return err
     }
+    if IsReservedName(name) {
+        return fmt.Errorf("%w: %q", ErrReservedName, name)
+    }
+    token := session.SanitizeToken(name)
+    for _, h := range hosts {
+        if h.Name != name && session.SanitizeToken(h.Name) == token {
+            return fmt.Errorf("%w: '%s' and '%s' share the session prefix schh_%s_", ErrHostCollision, name, h.Name, token)
+        }
+    }
     return nil
 }
 
-func RemoveHost(name string) error {
-    path, err := hostsFilePath()
+func UpdateHost(name string, update func(*Host) error) error {
+    path, index, hosts, err := personalHost(name)
+    if err != nil {
+        return err
+    }
+    updated := hosts[index]
+    if err := update(&updated); err != nil {
+        return err
+    }
+    if updated.Name != name {
+        return errors.New("use rename to change a host name")
+    }
+    if err := ValidateTarget(updated.Target); err != nil {
+        return err
+    }
+    hosts[index] = updated
+    if _, err := loadHosts(hosts); err != nil {
+        return err
+    }
+    return writeHostsFile(path, hosts)
+}
+
+func RenameHost(oldName, newName string) error {
+    path, index, hosts, err := personalHost(oldName)
     if err != nil {
         return err
     }
-    hosts, err := LoadHosts()
+    merged, err := LoadHosts()
     if err != nil {
         return err
     }
+    if FindHost(merged, newName) != nil {
+        return fmt.Errorf("%w: %s", ErrHostExists, newName)
+    }
+    others := make([]Host, 0, len(merged))
+    for _, h := range merged {
+        if h.Name != oldName {
+            others = append(others, h)
+        }
+    }
+    if err := CheckNewHost(others, newName); err != nil {
+        return err
+    }
+    hosts[index].Name = newName
+    for i := range hosts {
+        for j, hop := range hosts[i].Via {
+            if hop == oldName {
+                hosts[i].Via[j] = newName
+            }
+        }
+    }
+    if _, err := loadHosts(hosts); err != nil {
+        return err
+    }
+    return writeHostsFile(path, hosts)
+}
 
-    index := -1
+func personalHost(name string) (string, int, []Host, error) {
+    path, err := hostsFilePath()
+    if err != nil {
+        return "", -1, nil, err
+    }
+    hosts, err := readHostsFile(path)
+    if err != nil {
+        return "", -1, nil, err
+    }
     for i, h := range hosts {
         if h.Name == name {
-            index = i
-            break
+            return path, i, hosts, nil
         }
     }
-    if index == -1 {
-        return ErrHostNotFound
+    merged, err := LoadHosts()
+    if err != nil {
+        return "", -1, nil, err
+    }
+    if existing := FindHost(merged, name); existing != nil {
+        return "", -1, nil, fmt.Errorf("%w: %s", ErrReadOnlySource, existing.Source)
+    }
+    return "", -1, nil, ErrHostNotFound
+}
+
+func HostKind(h Host) string {
+    if h.Kind == "" {
+        return KindSSH
     }
+    return h.Kind
+}
 
-    hosts = append(hosts[:index], hosts[index+1:]...)
+func FormatHost(h Host) string {
+    return formatHostLine(h)
+}
+
+func ParseHost(line string) (Host, error) {
+    return parseHostLine(strings.TrimSpace(line))
+}
 
+func readHostsFile(path string) ([]Host, error) {
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return []Host{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    var hosts []Host
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        host, err := parseHostLine(line)
+        if err != nil {
+            return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+        }
+        host.Source = path
+        hosts = append(hosts, host)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return hosts, nil
+}
+
+func writeHostsFile(path string, hosts []Host) error {
     file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
     if err != nil {
         return err
@@ -159,14 +492,11 @@ func RemoveHost(name string) error {
 
     writer := bufio.NewWriter(file)
     for _, h := range hosts {
-        if _, err := fmt.Fprintf(writer, "%s %s\n", h.Name, h.Target); err != nil {
+        if _, err := fmt.Fprintln(writer, formatHostLine(h)); err != nil {
             return err
         }
     }
-    if err := writer.Flush(); err != nil {
-        return err
-    }
-    return nil
+    return writer.Flush()
 }
 
 func GetLastSessionLabel(hostName string) (string, error) {
@@ -227,6 +557,23 @@ func ClearLastSessionLabel(hostName string) (bool, error) {
     return true, nil
 }
 
+func MoveLastSessionLabel(oldHost, newHost string) (bool, error) {
+    entries, err := loadLabelEntries()
+    if err != nil {
+        return false, err
+    }
+    label, ok := entries[oldHost]
+    if !ok {
+        return false, nil
+    }
+    delete(entries, oldHost)
+    entries[newHost] = label
+    if err := saveLabelEntries(entries); err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
 func loadLabelEntries() (map[string]string, error) {
     path, err := lastSessionsFilePath()
     if err != nil {
@@ -279,3 +626,49 @@ func saveLabelEntries(entries map[string]string) error {
     }
     return writer.Flush()
 }
+
+type Paths struct {
+    ConfigDir string `json:"config_dir"`
+    Hosts     string `json:"hosts"`
+    Settings  string `json:"settings"`
+    SystemDir string `json:"system_dir"`
+    StateDir  string `json:"state_dir"`
+}
+
+func ResolvePaths() (Paths, error) {
+    var paths Paths
+    dir, err := resolveConfigDir()
+    if err != nil {
+        return paths, err
+    }
+    state, err := resolveStateDir()
+    if err != nil {
+        return paths, err
+    }
+    paths.ConfigDir = dir
+    paths.Hosts = filepath.Join(dir, "hosts")
+    paths.Settings = filepath.Join(dir, "config")
+    paths.SystemDir = SystemDir()
+    paths.StateDir = state
+    return paths, nil
+}
+
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+    tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(perm); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestMigrateLegacyStateFile(t *testing.T) {
+    home := t.TempDir()
+    state := t.TempDir()
+    override := t.TempDir()
+    t.Setenv("HOME", home)
+    t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+    t.Setenv("SCHH_CONFIG_DIR", override)
+    t.Setenv("SCHH_STATE_DIR", state)
+
+    legacy := writeTestFile(t, filepath.Join(home, ".config", "schh", "last_sessions"), "prod\tapi\n")
+    writeTestFile(t, filepath.Join(override, "audit.log"), "not state\n")
+
+    path, err := stateFilePath("last_sessions")
+    if err != nil {
+        t.Fatalf("stateFilePath: %v", err)
+    }
+    if data, err := os.ReadFile(path); err != nil || string(data) != "prod\tapi\n" {
+        t.Errorf("migrated file = %q, %v", data, err)
+    }
+    if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+        t.Errorf("legacy file still present: %v", err)
+    }
+
+    path, err = stateFilePath("audit.log")
+    if err != nil {
+        t.Fatalf("stateFilePath: %v", err)
+    }
+    if _, err := os.Stat(path); !os.IsNotExist(err) {
+        t.Errorf("file from SCHH_CONFIG_DIR was moved into the state directory: %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(override, "audit.log")); err != nil {
+        t.Errorf("file in SCHH_CONFIG_DIR was touched: %v", err)
+    }
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+type Forward struct {
+    Kind string
+    Spec string
+}
+
+func ParseForward(text string) (Forward, error) {
+    kind, spec, ok := strings.Cut(text, ":")
+    if !ok || spec == "" {
+        return Forward{}, fmt.Errorf("expected L:, R: or D: followed by an ssh forward spec, got %q", text)
+    }
+    kind = strings.ToUpper(kind)
+    if strings.ContainsAny(spec, " \t") {
+        return Forward{}, errors.New("forward specs cannot contain spaces")
+    }
+    switch kind {
+    case "L", "R":
+        if !strings.Contains(spec, ":") && !strings.Contains(spec, "/") {
+            return Forward{}, fmt.Errorf("-%s forwards need a destination, got %q", kind, spec)
+        }
+    case "D":
+    default:
+        return Forward{}, fmt.Errorf("unknown forward kind %q (expected L, R or D)", kind)
+    }
+    return Forward{Kind: kind, Spec: spec}, nil
+}
+
+func (f Forward) Args() []string {
+    return []string{"-" + f.Kind, f.Spec}
+}
+
+func (f Forward) String() string {
+    return "-" + f.Kind + " " + f.Spec
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+type SessionRecord struct {
+    ID           string
+    Host         string
+    Label        string
+    Forwards     []string
+    Created      time.Time
+    LastAttached time.Time
+}
+
+func sessionRecordsFilePath() (string, error) {
+    return stateFilePath("sessions")
+}
+
+func ScreenrcPath(sessionID string) (string, error) {
+    dir, err := ensureStateDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, "screenrc", sessionID), nil
+}
+
+func RemoveScreenrc(sessionID string) error {
+    path, err := ScreenrcPath(sessionID)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func SnapshotDir() (string, error) {
+    dir, err := ensureStateDir()
+    if err != nil {
+        return "", err
+    }
+    snapshots := filepath.Join(dir, "snapshots")
+    if err := os.MkdirAll(snapshots, 0o700); err != nil {
+        return "", err
+    }
+    return snapshots, nil
+}
+
+func ReadStatusCache(maxAge time.Duration) (string, bool) {
+    path, err := stateFilePath("status")
+    if err != nil {
+        return "", false
+    }
+    info, err := os.Stat(path)
+    if err != nil || time.Since(info.ModTime()) > maxAge {
+        return "", false
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return "", false
+    }
+    return string(data), true
+}
+
+func WriteStatusCache(status string) error {
+    path, err := stateFilePath("status")
+    if err != nil {
+        return err
+    }
+    return WriteFileAtomic(path, []byte(status), 0o644)
+}
+
+func LoadSessionRecords() (map[string]SessionRecord, error) {
+    path, err := sessionRecordsFilePath()
+    if err != nil {
+        return nil, err
+    }
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return map[string]SessionRecord{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    records := make(map[string]SessionRecord)
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fields, err := splitFields(line)
+        if err != nil || len(fields) == 0 {
+            continue
+        }
+        record := SessionRecord{ID: fields[0]}
+        for _, field := range fields[1:] {
+            key, value, _ := strings.Cut(field, "=")
+            switch key {
+            case "host":
+                record.Host = value
+            case "label":
+                record.Label = value
+            case "forwards":
+                if value != "" {
+                    record.Forwards = strings.Split(value, ",")
+                }
+            case "created":
+                record.Created, _ = time.Parse(time.RFC3339, value)
+            case "attached":
+                record.LastAttached, _ = time.Parse(time.RFC3339, value)
+            }
+        }
+        records[record.ID] = record
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return records, nil
+}
+
+func SaveSessionRecord(record SessionRecord) error {
+    return updateSessionRecords(func(records map[string]SessionRecord) bool {
+        records[record.ID] = record
+        return true
+    })
+}
+
+func TouchSessionRecord(record SessionRecord, attached time.Time) error {
+    return updateSessionRecords(func(records map[string]SessionRecord) bool {
+        if existing, ok := records[record.ID]; ok {
+            record = existing
+        }
+        record.LastAttached = attached
+        records[record.ID] = record
+        return true
+    })
+}
+
+func MarkAttached(ids []string, now time.Time) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    return updateSessionRecords(func(records map[string]SessionRecord) bool {
+        changed := false
+        for _, id := range ids {
+            record, ok := records[id]
+            if !ok || now.Sub(record.LastAttached) < time.Minute {
+                continue
+            }
+            record.LastAttached = now
+            records[id] = record
+            changed = true
+        }
+        return changed
+    })
+}
+
+func RenameSessionRecord(oldID, newID string) error {
+    err := updateSessionRecords(func(records map[string]SessionRecord) bool {
+        record, ok := records[oldID]
+        if !ok {
+            return false
+        }
+        delete(records, oldID)
+        record.ID = newID
+        records[newID] = record
+        return true
+    })
+    if err != nil {
+        return err
+    }
+    oldPath, err := ScreenrcPath(oldID)
+    if err != nil {
+        return err
+    }
+    newPath, err := ScreenrcPath(newID)
+    if err != nil {
+        return err
+    }
+    if err := os.Rename(oldPath, newPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func (r SessionRecord) LastActive() time.Time {
+    if r.LastAttached.After(r.Created) {
+        return r.LastAttached
+    }
+    return r.Created
+}
+
+func ExpiryReason(host Host, record SessionRecord, attached bool, now time.Time) string {
+    if host.MaxAge != "" && !record.Created.IsZero() {
+        maxAge, err := ParseDuration(host.MaxAge)
+        if err == nil && now.Sub(record.Created) > maxAge {
+            return fmt.Sprintf("older than %s", host.MaxAge)
+        }
+    }
+    if host.IdleTimeout != "" && !attached {
+        idle, err := ParseDuration(host.IdleTimeout)
+        if err == nil && !record.LastActive().IsZero() && now.Sub(record.LastActive()) > idle {
+            return fmt.Sprintf("not attached for more than %s", host.IdleTimeout)
+        }
+    }
+    return ""
+}
+
+func PruneSessionRecords(running map[string]bool) error {
+    var removed []string
+    err := updateSessionRecords(func(records map[string]SessionRecord) bool {
+        for id := range records {
+            if !running[id] {
+                delete(records, id)
+                removed = append(removed, id)
+            }
+        }
+        return len(removed) > 0
+    })
+    for _, id := range removed {
+        _ = RemoveScreenrc(id)
+    }
+    return err
+}
+
+func updateSessionRecords(update func(records map[string]SessionRecord) bool) error {
+    path, err := sessionRecordsFilePath()
+    if err != nil {
+        return err
+    }
+    unlock, err := lockFile(path)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+    records, err := LoadSessionRecords()
+    if err != nil {
+        return err
+    }
+    if !update(records) {
+        return nil
+    }
+    return saveSessionRecords(records)
+}
+
+func saveSessionRecords(records map[string]SessionRecord) error {
+    path, err := sessionRecordsFilePath()
+    if err != nil {
+        return err
+    }
+    ids := make([]string, 0, len(records))
+    for id := range records {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    var b strings.Builder
+    for _, id := range ids {
+        b.WriteString(formatSessionRecord(records[id]))
+        b.WriteByte('\n')
+    }
+    return WriteFileAtomic(path, []byte(b.String()), 0o644)
+}
+
+func formatSessionRecord(record SessionRecord) string {
+    fields := []string{
+        quoteField(record.ID),
+        quoteField("host=" + record.Host),
+        quoteField("label=" + record.Label),
+    }
+    if !record.Created.IsZero() {
+        fields = append(fields, "created="+record.Created.UTC().Format(time.RFC3339))
+    }
+    if !record.LastAttached.IsZero() {
+        fields = append(fields, "attached="+record.LastAttached.UTC().Format(time.RFC3339))
+    }
+    if len(record.Forwards) > 0 {
+        fields = append(fields, quoteField("forwards="+strings.Join(record.Forwards, ",")))
+    }
+    return strings.Join(fields, " ")
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestRenameSessionRecord(t *testing.T) {
+    t.Setenv("SCHH_STATE_DIR", t.TempDir())
+
+    created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+    record := SessionRecord{ID: "schh_old_api", Host: "old", Label: "api", Forwards: []string{"web"}, Created: created}
+    other := SessionRecord{ID: "schh_old_db", Host: "old", Label: "db", Created: created}
+    for _, r := range []SessionRecord{record, other} {
+        if err := SaveSessionRecord(r); err != nil {
+            t.Fatalf("SaveSessionRecord: %v", err)
+        }
+    }
+    oldPath, err := ScreenrcPath("schh_old_api")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.MkdirAll(filepath.Dir(oldPath), 0o700); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(oldPath, []byte("defscrollback 5000\n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := RenameSessionRecord("schh_old_api", "schh_new_api"); err != nil {
+        t.Fatalf("RenameSessionRecord: %v", err)
+    }
+
+    records, err := LoadSessionRecords()
+    if err != nil {
+        t.Fatalf("LoadSessionRecords: %v", err)
+    }
+    if _, ok := records["schh_old_api"]; ok {
+        t.Error("old record is still present")
+    }
+    want := record
+    want.ID = "schh_new_api"
+    if got := records["schh_new_api"]; !reflect.DeepEqual(got, want) {
+        t.Errorf("moved record = %+v, want %+v", got, want)
+    }
+    if got := records["schh_old_db"]; !reflect.DeepEqual(got, other) {
+        t.Errorf("unrelated record = %+v, want %+v", got, other)
+    }
+
+    newPath, _ := ScreenrcPath("schh_new_api")
+    if data, err := os.ReadFile(newPath); err != nil || string(data) != "defscrollback 5000\n" {
+        t.Errorf("screenrc at new path = %q, %v", data, err)
+    }
+    if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+        t.Errorf("screenrc still at old path: %v", err)
+    }
+
+    if err := RenameSessionRecord("schh_missing_x", "schh_missing_y"); err != nil {
+        t.Errorf("RenameSessionRecord without a record: %v", err)
+    }
+}
+
+func TestSessionRecordsConcurrentWrites(t *testing.T) {
+    t.Setenv("SCHH_STATE_DIR", t.TempDir())
+
+    var wg sync.WaitGroup
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            id := fmt.Sprintf("schh_host_s%d", i)
+            if err := SaveSessionRecord(SessionRecord{ID: id, Host: "host", Label: fmt.Sprintf("s%d", i)}); err != nil {
+                t.Error(err)
+            }
+            if err := MarkAttached([]string{id}, time.Now()); err != nil {
+                t.Error(err)
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    records, err := LoadSessionRecords()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(records) != 20 {
+        t.Errorf("kept %d of 20 records", len(records))
+    }
+}
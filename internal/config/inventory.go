@@ -0,0 +1,140 @@
+package config
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+type Inventory struct {
+    Name    string
+    Command string
+    TTL     time.Duration
+}
+
+const (
+    defaultInventoryTTL     = 5 * time.Minute
+    inventoryCommandTimeout = 30 * time.Second
+)
+
+func inventoryCachePath(name string) (string, error) {
+    dir, err := ensureStateDir()
+    if err != nil {
+        return "", err
+    }
+    cacheDir := filepath.Join(dir, "inventory")
+    if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+        return "", err
+    }
+    return filepath.Join(cacheDir, name+".json"), nil
+}
+
+func loadInventory(inv Inventory) ([]Host, error) {
+    cachePath, err := inventoryCachePath(inv.Name)
+    if err != nil {
+        return nil, err
+    }
+
+    cached, fresh := readInventoryCache(cachePath, inv.TTL)
+    if fresh {
+        if hosts, err := decodeInventory(inv, cached); err == nil {
+            return hosts, nil
+        }
+    }
+
+    output, runErr := runInventory(inv)
+    if runErr == nil {
+        hosts, err := decodeInventory(inv, output)
+        if err == nil {
+            if err := WriteFileAtomic(cachePath, output, 0o644); err != nil {
+                warnf("unable to cache inventory %s: %v", inv.Name, err)
+            }
+            return hosts, nil
+        }
+        runErr = err
+    }
+    if cached != nil {
+        if hosts, err := decodeInventory(inv, cached); err == nil {
+            return hosts, nil
+        }
+    }
+    return nil, fmt.Errorf("provider failed and no cached hosts are available: %w", runErr)
+}
+
+func readInventoryCache(path string, ttl time.Duration) ([]byte, bool) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, false
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, false
+    }
+    return data, ttl > 0 && time.Since(info.ModTime()) < ttl
+}
+
+func runInventory(inv Inventory) ([]byte, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), inventoryCommandTimeout)
+    defer cancel()
+
+    cmd := exec.CommandContext(ctx, inv.Command)
+    cmd.Env = append(os.Environ(), "SCHH_INVENTORY="+inv.Name)
+    var stdout, stderr bytes.Buffer
+    cmd.Stdout = &stdout
+    cmd.Stderr = &stderr
+    if err := cmd.Run(); err != nil {
+        if msg := strings.TrimSpace(stderr.String()); msg != "" {
+            return nil, fmt.Errorf("%w: %s", err, msg)
+        }
+        return nil, err
+    }
+    return stdout.Bytes(), nil
+}
+
+func decodeInventory(inv Inventory, data []byte) ([]Host, error) {
+    var records []Host
+    if err := json.Unmarshal(data, &records); err != nil {
+        return nil, fmt.Errorf("invalid inventory output: %w", err)
+    }
+    source := "inventory:" + inv.Name
+    hosts := make([]Host, 0, len(records))
+    for _, record := range records {
+        if record.Name == "" {
+            return nil, errors.New("invalid inventory output: host record without a name")
+        }
+        h, err := inventoryHost(hosts, record)
+        if err != nil {
+            return nil, fmt.Errorf("invalid inventory output: host %q: %w", record.Name, err)
+        }
+        h.Source = source
+        hosts = append(hosts, h)
+    }
+    return hosts, nil
+}
+
+func inventoryHost(hosts []Host, record Host) (Host, error) {
+    host := Host{Name: record.Name, Target: record.Target}
+    if err := CheckNewHost(hosts, host.Name); err != nil {
+        return host, err
+    }
+    if host.Target == "" {
+        host.Target = host.Name
+    }
+    if err := ValidateTarget(host.Target); err != nil {
+        return host, err
+    }
+    for _, attr := range HostAttrs(record) {
+        key, value, _ := strings.Cut(attr, "=")
+        if err := SetHostAttr(&host, key, value); err != nil {
+            return host, err
+        }
+    }
+    return host, nil
+}
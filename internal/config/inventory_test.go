@@ -0,0 +1,64 @@
+package config
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestDecodeInventory(t *testing.T) {
+    inv := Inventory{Name: "aws"}
+    data := `[
+        {"name": "api-1", "target": "deploy@10.0.3.17", "tags": ["api"], "via": ["bastion"]},
+        {"name": "db", "kind": "docker", "container": "pg", "idle_timeout": "2h", "forwards": {"pg": "L:5432:localhost:5432"}}
+    ]`
+    hosts, err := decodeInventory(inv, []byte(data))
+    if err != nil {
+        t.Fatalf("decodeInventory: %v", err)
+    }
+    want := []Host{
+        {Name: "api-1", Target: "deploy@10.0.3.17", Tags: []string{"api"}, Via: []string{"bastion"}, Source: "inventory:aws"},
+        {
+            Name:        "db",
+            Target:      "db",
+            Kind:        KindDocker,
+            Container:   "pg",
+            IdleTimeout: "2h",
+            Forwards:    map[string]string{"pg": "L:5432:localhost:5432"},
+            Source:      "inventory:aws",
+        },
+    }
+    if !reflect.DeepEqual(hosts, want) {
+        t.Errorf("decodeInventory =\n  %+v\nwant\n  %+v", hosts, want)
+    }
+}
+
+func TestDecodeInventoryErrors(t *testing.T) {
+    ReserveHostNames("status")
+    inv := Inventory{Name: "aws"}
+    tests := []struct {
+        name string
+        data string
+        want string
+    }{
+        {"not json", `api-1 deploy@api`, "invalid inventory output"},
+        {"missing name", `[{"target": "api"}]`, "host record without a name"},
+        {"name with spaces", `[{"name": "api 1"}]`, "invalid host name"},
+        {"name with slash", `[{"name": "api/1"}]`, "invalid host name"},
+        {"reserved name", `[{"name": "status"}]`, "reserved"},
+        {"session prefix collision", `[{"name": "api.1"}, {"name": "api_1"}]`, "share the session prefix"},
+        {"target option", `[{"name": "api", "target": "-oProxyCommand=x"}]`, `host "api"`},
+        {"unknown kind", `[{"name": "api", "kind": "vm"}]`, `unknown kind "vm"`},
+        {"bad forward", `[{"name": "api", "forwards": {"pg": "5432"}}]`, "forward pg"},
+        {"bad duration", `[{"name": "api", "max_age": "soon"}]`, "max_age"},
+        {"bad option name", `[{"name": "api", "options": {"Port ": "22"}}]`, "invalid ssh option name"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := decodeInventory(inv, []byte(tt.data))
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Fatalf("decodeInventory error = %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+    "os"
+    "syscall"
+)
+
+func lockFile(path string) (func(), error) {
+    file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+    if err != nil {
+        return nil, err
+    }
+    if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+        file.Close()
+        return nil, err
+    }
+    return func() {
+        syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+        file.Close()
+    }, nil
+}
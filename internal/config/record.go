@@ -0,0 +1,339 @@
+package config
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "schh/internal/hook"
+)
+
+func parseHostLine(line string) (Host, error) {
+    fields, err := splitFields(line)
+    if err != nil {
+        return Host{}, err
+    }
+    if len(fields) == 0 {
+        return Host{}, errors.New("empty host record")
+    }
+    host := Host{Name: fields[0], Target: fields[0]}
+    if len(fields) > 1 {
+        host.Target = fields[1]
+    }
+    if err := ValidateTarget(host.Target); err != nil {
+        return host, fmt.Errorf("host %s: %w", host.Name, err)
+    }
+    for _, field := range fields[min(len(fields), 2):] {
+        key, value, ok := strings.Cut(field, "=")
+        if !ok {
+            return host, fmt.Errorf("host %s: expected key=value, got %q", host.Name, field)
+        }
+        if err := SetHostAttr(&host, key, value); err != nil {
+            return host, fmt.Errorf("host %s: %w", host.Name, err)
+        }
+    }
+    return host, nil
+}
+
+func formatHostLine(h Host) string {
+    fields := []string{quoteField(h.Name), quoteField(h.Target)}
+    for _, attr := range HostAttrs(h) {
+        fields = append(fields, quoteField(attr))
+    }
+    return strings.Join(fields, " ")
+}
+
+func SetHostAttr(h *Host, key, value string) error {
+    switch {
+    case key == "tags":
+        h.Tags = nil
+        for _, tag := range strings.Split(value, ",") {
+            if tag = strings.TrimSpace(tag); tag != "" {
+                h.Tags = append(h.Tags, tag)
+            }
+        }
+    case key == "via":
+        h.Via = nil
+        for _, hop := range strings.Split(value, ",") {
+            if hop = strings.TrimSpace(hop); hop != "" {
+                h.Via = append(h.Via, hop)
+            }
+        }
+    case key == "kind":
+        if value != "" && !isKind(value) {
+            return fmt.Errorf("unknown kind %q (expected %s)", value, strings.Join(Kinds, ", "))
+        }
+        h.Kind = value
+    case key == "shell":
+        h.Shell = value
+    case (key == "container" || key == "pod" || key == "namespace" || key == "context") && strings.HasPrefix(value, "-"):
+        return fmt.Errorf("%s cannot start with '-'", key)
+    case key == "container":
+        h.Container = value
+    case key == "pod":
+        h.Pod = value
+    case key == "namespace":
+        h.Namespace = value
+    case key == "context":
+        h.Context = value
+    case key == "multiplex":
+        enabled, err := ParseSwitch(value)
+        if err != nil {
+            return fmt.Errorf("multiplex: %w", err)
+        }
+        h.Multiplex = enabled
+    case key == "control_persist":
+        if value != "" && value != "yes" && value != "no" {
+            if _, err := ParseDuration(value); err != nil {
+                return fmt.Errorf("control_persist: %w", err)
+            }
+        }
+        h.ControlPersist = value
+    case key == "idle_timeout" || key == "max_age":
+        if value != "" {
+            if _, err := ParseDuration(value); err != nil {
+                return fmt.Errorf("%s: %w", key, err)
+            }
+        }
+        if key == "idle_timeout" {
+            h.IdleTimeout = value
+        } else {
+            h.MaxAge = value
+        }
+    case key == "term":
+        if strings.ContainsAny(value, " \t=") {
+            return fmt.Errorf("invalid term %q", value)
+        }
+        h.Term = value
+    case key == "sendenv":
+        h.SendEnv = nil
+        for _, pattern := range strings.Split(value, ",") {
+            if pattern = strings.TrimSpace(pattern); pattern == "" {
+                continue
+            }
+            if strings.ContainsAny(pattern, " \t=") {
+                return fmt.Errorf("invalid sendenv pattern %q", pattern)
+            }
+            h.SendEnv = append(h.SendEnv, pattern)
+        }
+    case strings.HasPrefix(key, "env."):
+        name := strings.TrimPrefix(key, "env.")
+        if !isEnvName(name) {
+            return fmt.Errorf("invalid environment variable name %q", name)
+        }
+        setMapAttr(&h.Env, name, value)
+    case strings.HasPrefix(key, "screen."):
+        name := strings.TrimPrefix(key, "screen.")
+        if err := checkScreenOption(name, value); err != nil {
+            return err
+        }
+        setMapAttr(&h.Screen, name, value)
+    case strings.HasPrefix(key, "hook."):
+        event := strings.TrimPrefix(key, "hook.")
+        if !hook.IsEvent(event) {
+            return fmt.Errorf("unknown hook event %q", event)
+        }
+        setMapAttr(&h.Hooks, event, value)
+    case strings.HasPrefix(key, "option."):
+        name := strings.TrimPrefix(key, "option.")
+        if !isIdentifier(name) {
+            return fmt.Errorf("invalid ssh option name %q", name)
+        }
+        setMapAttr(&h.Options, name, value)
+    case strings.HasPrefix(key, "forward."):
+        name := strings.TrimPrefix(key, "forward.")
+        if !isIdentifier(name) {
+            return fmt.Errorf("invalid forward name %q", name)
+        }
+        if value != "" {
+            if _, err := ParseForward(value); err != nil {
+                return fmt.Errorf("forward %s: %w", name, err)
+            }
+        }
+        setMapAttr(&h.Forwards, name, value)
+    default:
+        return fmt.Errorf("unknown host attribute %q", key)
+    }
+    return nil
+}
+
+func HostAttrs(h Host) []string {
+    var attrs []string
+    if len(h.Tags) > 0 {
+        attrs = append(attrs, "tags="+strings.Join(h.Tags, ","))
+    }
+    if len(h.Via) > 0 {
+        attrs = append(attrs, "via="+strings.Join(h.Via, ","))
+    }
+    if h.Kind != "" {
+        attrs = append(attrs, "kind="+h.Kind)
+    }
+    if h.Shell != "" {
+        attrs = append(attrs, "shell="+h.Shell)
+    }
+    if h.Container != "" {
+        attrs = append(attrs, "container="+h.Container)
+    }
+    if h.Pod != "" {
+        attrs = append(attrs, "pod="+h.Pod)
+    }
+    if h.Namespace != "" {
+        attrs = append(attrs, "namespace="+h.Namespace)
+    }
+    if h.Context != "" {
+        attrs = append(attrs, "context="+h.Context)
+    }
+    if h.Multiplex {
+        attrs = append(attrs, "multiplex=on")
+    }
+    if h.ControlPersist != "" {
+        attrs = append(attrs, "control_persist="+h.ControlPersist)
+    }
+    if h.IdleTimeout != "" {
+        attrs = append(attrs, "idle_timeout="+h.IdleTimeout)
+    }
+    if h.MaxAge != "" {
+        attrs = append(attrs, "max_age="+h.MaxAge)
+    }
+    if h.Term != "" {
+        attrs = append(attrs, "term="+h.Term)
+    }
+    if len(h.SendEnv) > 0 {
+        attrs = append(attrs, "sendenv="+strings.Join(h.SendEnv, ","))
+    }
+    for _, key := range sortedKeys(h.Options) {
+        attrs = append(attrs, "option."+key+"="+h.Options[key])
+    }
+    for _, key := range sortedKeys(h.Env) {
+        attrs = append(attrs, "env."+key+"="+h.Env[key])
+    }
+    for _, key := range sortedKeys(h.Screen) {
+        attrs = append(attrs, "screen."+key+"="+h.Screen[key])
+    }
+    for _, key := range sortedKeys(h.Forwards) {
+        attrs = append(attrs, "forward."+key+"="+h.Forwards[key])
+    }
+    for _, key := range sortedKeys(h.Hooks) {
+        attrs = append(attrs, "hook."+key+"="+h.Hooks[key])
+    }
+    return attrs
+}
+
+func ParseSwitch(value string) (bool, error) {
+    switch strings.ToLower(value) {
+    case "on", "yes", "true":
+        return true, nil
+    case "", "off", "no", "false":
+        return false, nil
+    }
+    return false, fmt.Errorf("expected on or off, got %q", value)
+}
+
+func checkScreenOption(name, value string) error {
+    if value == "" {
+        return nil
+    }
+    switch name {
+    case "utf8":
+        if _, err := ParseSwitch(value); err != nil {
+            return fmt.Errorf("screen.utf8: %w", err)
+        }
+    case "scrollback":
+        if n, err := strconv.Atoi(value); err != nil || n < 0 {
+            return fmt.Errorf("screen.scrollback: expected a number of lines, got %q", value)
+        }
+    case "title":
+    default:
+        return fmt.Errorf("unknown screen option %q", name)
+    }
+    return nil
+}
+
+func isKind(value string) bool {
+    for _, kind := range Kinds {
+        if kind == value {
+            return true
+        }
+    }
+    return false
+}
+
+func isEnvName(name string) bool {
+    if name == "" || (name[0] >= '0' && name[0] <= '9') {
+        return false
+    }
+    for _, r := range name {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+        default:
+            return false
+        }
+    }
+    return true
+}
+
+func setMapAttr(m *map[string]string, key, value string) {
+    if value == "" {
+        delete(*m, key)
+        return
+    }
+    if *m == nil {
+        *m = make(map[string]string)
+    }
+    (*m)[key] = value
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func splitFields(line string) ([]string, error) {
+    var fields []string
+    var current strings.Builder
+    inField := false
+    inQuotes := false
+    escaped := false
+    for _, r := range line {
+        switch {
+        case escaped:
+            current.WriteRune(r)
+            escaped = false
+        case r == '\\' && inQuotes:
+            escaped = true
+        case r == '"':
+            inQuotes = !inQuotes
+            inField = true
+        case (r == ' ' || r == '\t') && !inQuotes:
+            if inField {
+                fields = append(fields, current.String())
+                current.Reset()
+                inField = false
+            }
+        default:
+            current.WriteRune(r)
+            inField = true
+        }
+    }
+    if inQuotes {
+        return nil, errors.New("unterminated quote")
+    }
+    if inField {
+        fields = append(fields, current.String())
+    }
+    return fields, nil
+}
+
+func quoteField(text string) string {
+    if text != "" && !strings.ContainsAny(text, " \t\"\\") {
+        return text
+    }
+    replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+    return `"` + replacer.Replace(text) + `"`
+}
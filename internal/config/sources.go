@@ -0,0 +1,315 @@
+package config
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "schh/internal/hook"
+)
+
+type Source struct {
+    Path      string
+    ReadOnly  bool
+    Inventory *Inventory
+}
+
+type Settings struct {
+    Sources        []string
+    Inventories    []Inventory
+    Hooks          map[string][]string
+    AuditRetention time.Duration
+    Backend        string
+    UpgradeFeed    string
+}
+
+func SystemDir() string {
+    if dir := os.Getenv("SCHH_SYSTEM_DIR"); dir != "" {
+        return dir
+    }
+    return "/etc/schh"
+}
+
+func settingsFilePath() (string, error) {
+    dir, err := resolveConfigDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, "config"), nil
+}
+
+func HostSources() ([]Source, error) {
+    personal, err := hostsFilePath()
+    if err != nil {
+        return nil, err
+    }
+    settings, err := LoadSettings()
+    if err != nil {
+        return nil, err
+    }
+
+    sources := []Source{{Path: personal}}
+    for _, path := range settings.Sources {
+        sources = append(sources, Source{Path: path, ReadOnly: true})
+    }
+    for i := range settings.Inventories {
+        inv := settings.Inventories[i]
+        sources = append(sources, Source{Path: "inventory:" + inv.Name, ReadOnly: true, Inventory: &inv})
+    }
+    sources = append(sources, Source{Path: filepath.Join(SystemDir(), "hosts.d"), ReadOnly: true})
+    return sources, nil
+}
+
+func loadSource(src Source) ([]Host, error) {
+    if src.Inventory != nil {
+        return loadInventory(*src.Inventory)
+    }
+    info, err := os.Stat(src.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        return []Host{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return readCatalogFile(src.Path)
+    }
+
+    entries, err := os.ReadDir(src.Path)
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(entries))
+    for _, entry := range entries {
+        name := entry.Name()
+        if entry.IsDir() || strings.HasPrefix(name, ".") || !isCatalogFile(name) {
+            continue
+        }
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var hosts []Host
+    for _, name := range names {
+        loaded, err := readCatalogFile(filepath.Join(src.Path, name))
+        if err != nil {
+            warnf("skipping host catalog %v", err)
+            continue
+        }
+        hosts = append(hosts, loaded...)
+    }
+    return hosts, nil
+}
+
+func isCatalogFile(name string) bool {
+    switch filepath.Ext(name) {
+    case "", ".toml", ".hosts", ".conf":
+        return !strings.HasSuffix(name, "~")
+    }
+    return false
+}
+
+func readCatalogFile(path string) ([]Host, error) {
+    if filepath.Ext(path) == ".toml" {
+        return readTOMLHostsFile(path)
+    }
+    return readHostsFile(path)
+}
+
+func LoadSettings() (Settings, error) {
+    var settings Settings
+    path, err := settingsFilePath()
+    if err != nil {
+        return settings, err
+    }
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return settings, nil
+    }
+    if err != nil {
+        return settings, err
+    }
+    defer file.Close()
+
+    base := filepath.Dir(path)
+    scanner := bufio.NewScanner(file)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fields := strings.Fields(line)
+        switch fields[0] {
+        case "source":
+            if len(fields) != 2 {
+                return settings, fmt.Errorf("%s:%d: source expects a single path", path, lineNo)
+            }
+            expanded, err := expandPath(fields[1], base)
+            if err != nil {
+                return settings, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+            }
+            settings.Sources = append(settings.Sources, expanded)
+        case "hook":
+            if len(fields) != 3 {
+                return settings, fmt.Errorf("%s:%d: hook expects an event and a command", path, lineNo)
+            }
+            if !hook.IsEvent(fields[1]) {
+                return settings, fmt.Errorf("%s:%d: unknown hook event %q", path, lineNo, fields[1])
+            }
+            command, err := expandCommand(fields[2], base)
+            if err != nil {
+                return settings, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+            }
+            if settings.Hooks == nil {
+                settings.Hooks = make(map[string][]string)
+            }
+            settings.Hooks[fields[1]] = append(settings.Hooks[fields[1]], command)
+        case "audit-retention":
+            if len(fields) != 2 {
+                return settings, fmt.Errorf("%s:%d: audit-retention expects a duration", path, lineNo)
+            }
+            retention, err := ParseDuration(fields[1])
+            if err != nil {
+                return settings, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+            }
+            settings.AuditRetention = retention
+        case "backend":
+            if len(fields) != 2 {
+                return settings, fmt.Errorf("%s:%d: backend expects a single name", path, lineNo)
+            }
+            settings.Backend = fields[1]
+        case "upgrade-feed":
+            if len(fields) != 2 {
+                return settings, fmt.Errorf("%s:%d: upgrade-feed expects a URL or a path", path, lineNo)
+            }
+            feed := fields[1]
+            if !strings.Contains(feed, "://") {
+                expanded, err := expandPath(feed, base)
+                if err != nil {
+                    return settings, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+                }
+                feed = expanded
+            }
+            settings.UpgradeFeed = feed
+        case "inventory":
+            inv, err := parseInventory(fields[1:], base)
+            if err != nil {
+                return settings, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+            }
+            for _, existing := range settings.Inventories {
+                if existing.Name == inv.Name {
+                    return settings, fmt.Errorf("%s:%d: inventory %q is declared twice", path, lineNo, inv.Name)
+                }
+            }
+            settings.Inventories = append(settings.Inventories, inv)
+        default:
+            return settings, fmt.Errorf("%s:%d: unknown setting %q", path, lineNo, fields[0])
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return settings, err
+    }
+    return settings, nil
+}
+
+func parseInventory(args []string, base string) (Inventory, error) {
+    if len(args) < 2 {
+        return Inventory{}, errors.New("inventory expects a name and a command")
+    }
+    inv := Inventory{Name: args[0], Command: args[1], TTL: defaultInventoryTTL}
+    if !isIdentifier(inv.Name) {
+        return inv, fmt.Errorf("invalid inventory name %q", inv.Name)
+    }
+    command, err := expandCommand(inv.Command, base)
+    if err != nil {
+        return inv, err
+    }
+    inv.Command = command
+    for _, arg := range args[2:] {
+        key, value, ok := strings.Cut(arg, "=")
+        if !ok || key != "ttl" {
+            return inv, fmt.Errorf("unknown inventory option %q", arg)
+        }
+        ttl, err := ParseDuration(value)
+        if err != nil {
+            return inv, err
+        }
+        inv.TTL = ttl
+    }
+    return inv, nil
+}
+
+func expandCommand(command, base string) (string, error) {
+    if !strings.Contains(command, "/") && !strings.HasPrefix(command, "~") {
+        return command, nil
+    }
+    return expandPath(command, base)
+}
+
+func expandPath(path, base string) (string, error) {
+    if path == "~" || strings.HasPrefix(path, "~/") {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return "", err
+        }
+        path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+    }
+    if !filepath.IsAbs(path) {
+        path = filepath.Join(base, path)
+    }
+    return filepath.Clean(path), nil
+}
+
+func HookCommands(settings Settings, host Host, event string) []string {
+    commands := append([]string{}, settings.Hooks[event]...)
+    if command := host.Hooks[event]; command != "" {
+        if expanded, err := expandCommand(command, filepath.Dir(host.Source)); err == nil {
+            command = expanded
+        }
+        commands = append(commands, command)
+    }
+    return commands
+}
+
+func isIdentifier(name string) bool {
+    if name == "" {
+        return false
+    }
+    for _, r := range name {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+        default:
+            return false
+        }
+    }
+    return true
+}
+
+func ParseDuration(text string) (time.Duration, error) {
+    for _, unit := range []struct {
+        suffix string
+        size   time.Duration
+    }{{"d", 24 * time.Hour}, {"w", 7 * 24 * time.Hour}} {
+        if !strings.HasSuffix(text, unit.suffix) {
+            continue
+        }
+        count, err := strconv.Atoi(strings.TrimSuffix(text, unit.suffix))
+        if err != nil || count < 0 {
+            return 0, fmt.Errorf("invalid duration %q", text)
+        }
+        return time.Duration(count) * unit.size, nil
+    }
+    d, err := time.ParseDuration(text)
+    if err != nil || d < 0 {
+        return 0, fmt.Errorf("invalid duration %q", text)
+    }
+    return d, nil
+}
@@ -0,0 +1,402 @@
+package config
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+var tomlTables = map[string]string{
+    "options":  "option.",
+    "forwards": "forward.",
+    "env":      "env.",
+    "screen":   "screen.",
+    "hooks":    "hook.",
+}
+
+func readTOMLHostsFile(path string) ([]Host, error) {
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return []Host{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    doc, err := parseTOML(bufio.NewScanner(file))
+    if err != nil {
+        return nil, fmt.Errorf("%s:%w", path, err)
+    }
+    for key := range doc {
+        if key != "hosts" {
+            return nil, fmt.Errorf("%s: unknown top-level key %q", path, key)
+        }
+    }
+    table, ok := doc["hosts"].(map[string]any)
+    if !ok && doc["hosts"] != nil {
+        return nil, fmt.Errorf("%s: hosts must be a table", path)
+    }
+    names := make([]string, 0, len(table))
+    for name := range table {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    hosts := make([]Host, 0, len(names))
+    for _, name := range names {
+        fields, ok := table[name].(map[string]any)
+        if !ok {
+            return nil, fmt.Errorf("%s: hosts.%s must be a table", path, name)
+        }
+        host, err := hostFromTOML(name, fields)
+        if err != nil {
+            return nil, fmt.Errorf("%s: host %q: %w", path, name, err)
+        }
+        host.Source = path
+        hosts = append(hosts, host)
+    }
+    return hosts, nil
+}
+
+func hostFromTOML(name string, fields map[string]any) (Host, error) {
+    host := Host{Name: name, Target: name}
+    if err := ValidateHostName(name); err != nil {
+        return host, err
+    }
+    keys := make([]string, 0, len(fields))
+    for key := range fields {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        value := fields[key]
+        if prefix, ok := tomlTables[key]; ok {
+            sub, ok := value.(map[string]any)
+            if !ok {
+                return host, fmt.Errorf("%s must be a table", key)
+            }
+            for subKey, subValue := range sub {
+                text, err := tomlScalar(subValue)
+                if err != nil {
+                    return host, fmt.Errorf("%s.%s: %w", key, subKey, err)
+                }
+                if err := SetHostAttr(&host, prefix+subKey, text); err != nil {
+                    return host, err
+                }
+            }
+            continue
+        }
+        text, err := tomlScalar(value)
+        if err != nil {
+            return host, fmt.Errorf("%s: %w", key, err)
+        }
+        if key == "target" {
+            if err := ValidateTarget(text); err != nil {
+                return host, err
+            }
+            host.Target = text
+            continue
+        }
+        if err := SetHostAttr(&host, key, text); err != nil {
+            return host, err
+        }
+    }
+    return host, nil
+}
+
+func tomlScalar(value any) (string, error) {
+    switch v := value.(type) {
+    case string:
+        return v, nil
+    case bool:
+        if v {
+            return "on", nil
+        }
+        return "off", nil
+    case int64:
+        return strconv.FormatInt(v, 10), nil
+    case []any:
+        items := make([]string, 0, len(v))
+        for _, item := range v {
+            text, err := tomlScalar(item)
+            if err != nil {
+                return "", err
+            }
+            if _, nested := item.([]any); nested || strings.Contains(text, ",") {
+                return "", errors.New("array items cannot contain commas or arrays")
+            }
+            items = append(items, text)
+        }
+        return strings.Join(items, ","), nil
+    }
+    return "", errors.New("unsupported value")
+}
+
+func parseTOML(scanner *bufio.Scanner) (map[string]any, error) {
+    doc := make(map[string]any)
+    table := doc
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := stripTOMLComment(scanner.Text())
+        if line == "" {
+            continue
+        }
+        if strings.HasPrefix(line, "[[") {
+            return nil, fmt.Errorf("%d: arrays of tables are not supported", lineNo)
+        }
+        if strings.HasPrefix(line, "[") {
+            path, rest, err := parseTOMLKey(line[1:], ']')
+            if err != nil {
+                return nil, fmt.Errorf("%d: %w", lineNo, err)
+            }
+            if rest = strings.TrimSpace(rest); rest != "" {
+                return nil, fmt.Errorf("%d: unexpected %q after table header", lineNo, rest)
+            }
+            table, err = tomlTable(doc, path)
+            if err != nil {
+                return nil, fmt.Errorf("%d: %w", lineNo, err)
+            }
+            continue
+        }
+
+        path, rest, err := parseTOMLKey(line, '=')
+        if err != nil {
+            return nil, fmt.Errorf("%d: %w", lineNo, err)
+        }
+        for strings.HasPrefix(strings.TrimSpace(rest), "[") && !tomlArrayClosed(rest) && scanner.Scan() {
+            lineNo++
+            rest += " " + stripTOMLComment(scanner.Text())
+        }
+        value, rest, err := parseTOMLValue(strings.TrimSpace(rest))
+        if err != nil {
+            return nil, fmt.Errorf("%d: %w", lineNo, err)
+        }
+        if rest = strings.TrimSpace(rest); rest != "" {
+            return nil, fmt.Errorf("%d: unexpected %q after value", lineNo, rest)
+        }
+        parent, err := tomlTable(table, path[:len(path)-1])
+        if err != nil {
+            return nil, fmt.Errorf("%d: %w", lineNo, err)
+        }
+        key := path[len(path)-1]
+        if _, exists := parent[key]; exists {
+            return nil, fmt.Errorf("%d: duplicate key %q", lineNo, key)
+        }
+        parent[key] = value
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return doc, nil
+}
+
+func tomlTable(root map[string]any, path []string) (map[string]any, error) {
+    table := root
+    for _, key := range path {
+        next, exists := table[key]
+        if !exists {
+            created := make(map[string]any)
+            table[key] = created
+            table = created
+            continue
+        }
+        sub, ok := next.(map[string]any)
+        if !ok {
+            return nil, fmt.Errorf("key %q is not a table", key)
+        }
+        table = sub
+    }
+    return table, nil
+}
+
+func parseTOMLKey(text string, end byte) ([]string, string, error) {
+    var path []string
+    for {
+        text = strings.TrimLeft(text, " \t")
+        if text == "" {
+            return nil, "", errors.New("missing key")
+        }
+        var part string
+        switch text[0] {
+        case '"', '\'':
+            value, rest, err := parseTOMLString(text)
+            if err != nil {
+                return nil, "", err
+            }
+            part, text = value, rest
+        default:
+            i := 0
+            for i < len(text) && isTOMLBareKeyChar(text[i]) {
+                i++
+            }
+            if i == 0 {
+                return nil, "", fmt.Errorf("invalid key near %q", text)
+            }
+            part, text = text[:i], text[i:]
+        }
+        path = append(path, part)
+        text = strings.TrimLeft(text, " \t")
+        switch {
+        case strings.HasPrefix(text, "."):
+            text = text[1:]
+        case text != "" && text[0] == end:
+            return path, text[1:], nil
+        default:
+            return nil, "", fmt.Errorf("expected %q after key", string(end))
+        }
+    }
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+    return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+func parseTOMLValue(text string) (any, string, error) {
+    switch {
+    case text == "":
+        return nil, "", errors.New("missing value")
+    case text[0] == '"' || text[0] == '\'':
+        return parseTOMLString(text)
+    case text[0] == '[':
+        return parseTOMLArray(text[1:])
+    case text[0] == '{':
+        return nil, "", errors.New("inline tables are not supported")
+    case strings.HasPrefix(text, "true"):
+        return true, text[4:], nil
+    case strings.HasPrefix(text, "false"):
+        return false, text[5:], nil
+    }
+    i := 0
+    for i < len(text) && strings.IndexByte("+-0123456789_", text[i]) >= 0 {
+        i++
+    }
+    n, err := strconv.ParseInt(strings.ReplaceAll(text[:i], "_", ""), 10, 64)
+    if i == 0 || err != nil {
+        return nil, "", fmt.Errorf("unsupported value near %q", text)
+    }
+    return n, text[i:], nil
+}
+
+func parseTOMLArray(text string) (any, string, error) {
+    items := []any{}
+    for {
+        text = strings.TrimLeft(text, " \t")
+        if strings.HasPrefix(text, "]") {
+            return items, text[1:], nil
+        }
+        item, rest, err := parseTOMLValue(text)
+        if err != nil {
+            return nil, "", err
+        }
+        items = append(items, item)
+        text = strings.TrimLeft(rest, " \t")
+        switch {
+        case strings.HasPrefix(text, ","):
+            text = text[1:]
+        case strings.HasPrefix(text, "]"):
+        default:
+            return nil, "", errors.New("expected ',' or ']' in array")
+        }
+    }
+}
+
+func tomlArrayClosed(text string) bool {
+    depth := 0
+    var quote byte
+    for i := 0; i < len(text); i++ {
+        c := text[i]
+        switch {
+        case quote != 0:
+            if c == '\\' && quote == '"' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '[':
+            depth++
+        case c == ']':
+            depth--
+        }
+    }
+    return depth <= 0
+}
+
+func stripTOMLComment(line string) string {
+    var quote byte
+    for i := 0; i < len(line); i++ {
+        c := line[i]
+        switch {
+        case quote != 0:
+            if c == '\\' && quote == '"' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '#':
+            return strings.TrimSpace(line[:i])
+        }
+    }
+    return strings.TrimSpace(line)
+}
+
+func parseTOMLString(text string) (string, string, error) {
+    quote := text[0]
+    if quote == '\'' {
+        end := strings.IndexByte(text[1:], '\'')
+        if end < 0 {
+            return "", "", errors.New("unterminated string")
+        }
+        return text[1 : end+1], text[end+2:], nil
+    }
+    var b strings.Builder
+    for i := 1; i < len(text); i++ {
+        c := text[i]
+        switch {
+        case c == '"':
+            return b.String(), text[i+1:], nil
+        case c != '\\':
+            b.WriteByte(c)
+            continue
+        }
+        i++
+        if i >= len(text) {
+            break
+        }
+        switch text[i] {
+        case '"', '\\':
+            b.WriteByte(text[i])
+        case 'n':
+            b.WriteByte('\n')
+        case 't':
+            b.WriteByte('\t')
+        case 'u', 'U':
+            size := 4
+            if text[i] == 'U' {
+                size = 8
+            }
+            if i+size >= len(text) {
+                return "", "", errors.New("invalid unicode escape")
+            }
+            code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+            if err != nil || !utf8.ValidRune(rune(code)) {
+                return "", "", errors.New("invalid unicode escape")
+            }
+            b.WriteRune(rune(code))
+            i += size
+        default:
+            return "", "", fmt.Errorf("invalid escape \\%c", text[i])
+        }
+    }
+    return "", "", errors.New("unterminated string")
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+    "bufio"
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseTOML(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+        want map[string]any
+    }{
+        {
+            name: "escapes",
+            data: `basic = "tab\there \"q\" back\\slash \u00e9\U0001F600"` + "\n" + `literal = 'C:\keys\id'` + "\n",
+            want: map[string]any{
+                "basic":   "tab\there \"q\" back\\slash \u00e9\U0001F600",
+                "literal": `C:\keys\id`,
+            },
+        },
+        {
+            name: "comments",
+            data: "# header\ns = \"a # b\" # trailing\nt = 'x#y'\n",
+            want: map[string]any{"s": "a # b", "t": "x#y"},
+        },
+        {
+            name: "scalars",
+            data: "n = 1_000\nneg = -5\nyes = true\nno = false\n",
+            want: map[string]any{"n": int64(1000), "neg": int64(-5), "yes": true, "no": false},
+        },
+        {
+            name: "multi-line array",
+            data: "tags = [\n    \"web\", # front\n    \"eu\",\n]\nempty = []\nnested = [[1, 2], ['a]']]\n",
+            want: map[string]any{
+                "tags":   []any{"web", "eu"},
+                "empty":  []any{},
+                "nested": []any{[]any{int64(1), int64(2)}, []any{"a]"}},
+            },
+        },
+        {
+            name: "dotted keys",
+            data: "[hosts]\nprod.options.Port = \"22\"\n\"db.eu\".target = \"db\"\n\n[hosts.prod]\ntarget = \"deploy@prod\"\n",
+            want: map[string]any{"hosts": map[string]any{
+                "prod":  map[string]any{"options": map[string]any{"Port": "22"}, "target": "deploy@prod"},
+                "db.eu": map[string]any{"target": "db"},
+            }},
+        },
+        {
+            name: "quoted table header",
+            data: "[ hosts . \"web 1\" ]\ntarget = \"web\"\n",
+            want: map[string]any{"hosts": map[string]any{"web 1": map[string]any{"target": "web"}}},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := parseTOML(bufio.NewScanner(strings.NewReader(tt.data)))
+            if err != nil {
+                t.Fatalf("parseTOML: %v", err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("parseTOML =\n  %#v\nwant\n  %#v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+        want string
+    }{
+        {"array of tables", "[[hosts]]\n", "1: arrays of tables are not supported"},
+        {"duplicate key", "a = 1\nb = 2\na = 3\n", `3: duplicate key "a"`},
+        {"duplicate dotted key", "[hosts.web]\ntarget = \"a\"\n[hosts]\nweb.target = \"b\"\n", `duplicate key "target"`},
+        {"inline table", "a = {b = 1}\n", "inline tables are not supported"},
+        {"unterminated string", "a = \"abc\n", "unterminated string"},
+        {"unterminated literal", "a = 'abc\n", "unterminated string"},
+        {"invalid escape", `a = "\q"` + "\n", `invalid escape \q`},
+        {"invalid unicode escape", `a = "\u12"` + "\n", "invalid unicode escape"},
+        {"value after string", "a = \"x\" y\n", `unexpected "y" after value`},
+        {"text after header", "[hosts] x\n", "unexpected \"x\" after table header"},
+        {"missing value", "a =\n", "missing value"},
+        {"missing equals", "a\n", `expected "=" after key`},
+        {"unsupported value", "a = yes\n", "unsupported value"},
+        {"float", "a = 1.5\n", `unexpected ".5" after value`},
+        {"key is not a table", "a = 1\n[a.b]\n", `key "a" is not a table`},
+        {"unclosed array", "a = [1, 2\n", "expected ',' or ']' in array"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := parseTOML(bufio.NewScanner(strings.NewReader(tt.data)))
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Fatalf("parseTOML error = %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestReadTOMLHostsFile(t *testing.T) {
+    path := writeTestFile(t, filepath.Join(t.TempDir(), "team.toml"), `
+[hosts.prod]
+target = "deploy@prod.example.com"
+tags = ["web", "eu"]
+multiplex = true
+idle_timeout = "2h"
+
+[hosts.prod.options]
+Port = 2222
+
+[hosts.prod.forwards]
+grafana = "L:3000:localhost:3000"
+
+[hosts.prod.env]
+APP = "api"
+
+[hosts.prod.screen]
+scrollback = 5000
+utf8 = false
+
+[hosts.prod.hooks]
+pre-attach = "notify-send attach"
+
+[hosts."db.eu"]
+via = ["prod"]
+`)
+    hosts, err := readTOMLHostsFile(path)
+    if err != nil {
+        t.Fatalf("readTOMLHostsFile: %v", err)
+    }
+    want := []Host{
+        {Name: "db.eu", Target: "db.eu", Via: []string{"prod"}, Source: path},
+        {
+            Name:        "prod",
+            Target:      "deploy@prod.example.com",
+            Tags:        []string{"web", "eu"},
+            Multiplex:   true,
+            IdleTimeout: "2h",
+            Options:     map[string]string{"Port": "2222"},
+            Forwards:    map[string]string{"grafana": "L:3000:localhost:3000"},
+            Env:         map[string]string{"APP": "api"},
+            Screen:      map[string]string{"scrollback": "5000", "utf8": "off"},
+            Hooks:       map[string]string{"pre-attach": "notify-send attach"},
+            Source:      path,
+        },
+    }
+    if !reflect.DeepEqual(hosts, want) {
+        t.Errorf("readTOMLHostsFile =\n  %+v\nwant\n  %+v", hosts, want)
+    }
+
+    missing, err := readTOMLHostsFile(filepath.Join(t.TempDir(), "missing.toml"))
+    if err != nil || len(missing) != 0 {
+        t.Errorf("missing file = %v, %v; want no hosts", missing, err)
+    }
+}
+
+func TestReadTOMLHostsFileErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+        want string
+    }{
+        {"unknown top-level key", "[servers.web]\ntarget = \"web\"\n", `unknown top-level key "servers"`},
+        {"hosts not a table", "hosts = \"web\"\n", "hosts must be a table"},
+        {"host not a table", "[hosts]\nweb = \"web\"\n", "hosts.web must be a table"},
+        {"sub-table not a table", "[hosts.web]\noptions = \"Port=22\"\n", "options must be a table"},
+        {"unknown attribute", "[hosts.web]\nuser = \"root\"\n", `unknown host attribute "user"`},
+        {"bad forward", "[hosts.web.forwards]\ndb = \"X:1\"\n", "forward db"},
+        {"bad kind", "[hosts.web]\nkind = \"vm\"\n", `unknown kind "vm"`},
+        {"bad duration", "[hosts.web]\nmax_age = \"soon\"\n", "max_age"},
+        {"bad target", "[hosts.web]\ntarget = \"-oProxyCommand=x\"\n", `host "web"`},
+        {"bad host name", "[hosts.\"web 1\"]\n", `host "web 1"`},
+        {"array with commas", "[hosts.web]\ntags = [\"a,b\"]\n", "cannot contain commas"},
+        {"nested array", "[hosts.web]\ntags = [[\"a\"]]\n", "cannot contain commas or arrays"},
+        {"parse error line", "\n\n[[hosts]]\n", "team.toml:3: arrays of tables"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := writeTestFile(t, filepath.Join(t.TempDir(), "team.toml"), tt.data)
+            _, err := readTOMLHostsFile(path)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Fatalf("readTOMLHostsFile error = %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestLoadHostsPrecedence(t *testing.T) {
+    configDir, systemDir := setupTestDirs(t)
+    shared := t.TempDir()
+
+    writeTestFile(t, filepath.Join(configDir, "hosts"), "web deploy@personal\n")
+    writeTestFile(t, filepath.Join(shared, "team.toml"), "[hosts.web]\ntarget = \"deploy@team\"\n\n[hosts.db]\ntarget = \"dba@team\"\n")
+    writeTestFile(t, filepath.Join(shared, "extra.hosts"), "db dba@extra\ncache cache@extra\n")
+    writeTestFile(t, filepath.Join(shared, "README.md"), "not a catalog\n")
+    writeTestFile(t, filepath.Join(shared, "broken.conf"), "broken -bad\n")
+    inventory := writeTestFile(t, filepath.Join(t.TempDir(), "inventory"), "#!/bin/sh\necho '[{\"name\": \"cache\", \"target\": \"cache@inventory\"}, {\"name\": \"api\", \"target\": \"api@inventory\"}]'\n")
+    if err := os.Chmod(inventory, 0o755); err != nil {
+        t.Fatal(err)
+    }
+    writeTestFile(t, filepath.Join(configDir, "config"), "source "+shared+"\ninventory aws "+inventory+" ttl=0\n")
+    system := filepath.Join(systemDir, "hosts.d")
+    writeTestFile(t, filepath.Join(system, "base"), "api api@system\nbastion jump@system\n")
+
+    var warnings strings.Builder
+    SetWarnings(&warnings)
+    defer SetWarnings(os.Stderr)
+    hosts, err := LoadHosts()
+    if err != nil {
+        t.Fatalf("LoadHosts: %v", err)
+    }
+    if !strings.Contains(warnings.String(), "broken.conf") {
+        t.Errorf("no warning about the broken catalog, got %q", warnings.String())
+    }
+    want := []struct{ name, target, source string }{
+        {"web", "deploy@personal", filepath.Join(configDir, "hosts")},
+        {"db", "dba@extra", filepath.Join(shared, "extra.hosts")},
+        {"cache", "cache@extra", filepath.Join(shared, "extra.hosts")},
+        {"api", "api@inventory", "inventory:aws"},
+        {"bastion", "jump@system", filepath.Join(system, "base")},
+    }
+    if len(hosts) != len(want) {
+        t.Fatalf("LoadHosts returned %d hosts, want %d: %+v", len(hosts), len(want), hosts)
+    }
+    for i, w := range want {
+        h := hosts[i]
+        if h.Name != w.name || h.Target != w.target || h.Source != w.source {
+            t.Errorf("host %d = %s %s (%s), want %s %s (%s)", i, h.Name, h.Target, h.Source, w.name, w.target, w.source)
+        }
+    }
+}
+
+func TestReadOnlySource(t *testing.T) {
+    configDir, systemDir := setupTestDirs(t)
+    writeTestFile(t, filepath.Join(configDir, "hosts"), "mine me@mine\n")
+    shared := writeTestFile(t, filepath.Join(systemDir, "hosts.d", "team.toml"), "[hosts.shared]\ntarget = \"ops@shared\"\n")
+
+    if _, err := RemoveHost("shared"); !errors.Is(err, ErrReadOnlySource) || !strings.Contains(err.Error(), shared) {
+        t.Errorf("RemoveHost error = %v, want ErrReadOnlySource naming %s", err, shared)
+    }
+    err := UpdateHost("shared", func(h *Host) error {
+        h.Target = "root@shared"
+        return nil
+    })
+    if !errors.Is(err, ErrReadOnlySource) {
+        t.Errorf("UpdateHost error = %v, want ErrReadOnlySource", err)
+    }
+    if err := RenameHost("shared", "other"); !errors.Is(err, ErrReadOnlySource) {
+        t.Errorf("RenameHost error = %v, want ErrReadOnlySource", err)
+    }
+    if _, err := RemoveHost("missing"); !errors.Is(err, ErrHostNotFound) {
+        t.Errorf("RemoveHost of an unknown host: error = %v, want ErrHostNotFound", err)
+    }
+
+    hosts, err := LoadHosts()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h := FindHost(hosts, "shared"); h == nil || h.Target != "ops@shared" {
+        t.Errorf("shared host changed: %+v", h)
+    }
+    if _, err := RemoveHost("mine"); err != nil {
+        t.Errorf("RemoveHost of a personal host: %v", err)
+    }
+}
+
+func setupTestDirs(t *testing.T) (string, string) {
+    t.Helper()
+    configDir, systemDir := t.TempDir(), t.TempDir()
+    t.Setenv("SCHH_CONFIG_DIR", configDir)
+    t.Setenv("SCHH_SYSTEM_DIR", systemDir)
+    t.Setenv("SCHH_STATE_DIR", t.TempDir())
+    return configDir, systemDir
+}
+
+func writeTestFile(t *testing.T, path, data string) string {
+    t.Helper()
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
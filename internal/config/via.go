@@ -0,0 +1,75 @@
+package config
+
+import (
+    "fmt"
+    "strings"
+)
+
+func validateHosts(hosts []Host, strict map[string]bool) ([]Host, error) {
+    for {
+        valid := make([]Host, 0, len(hosts))
+        for _, h := range hosts {
+            err := checkHostVia(hosts, h)
+            if err == nil {
+                valid = append(valid, h)
+                continue
+            }
+            if strict[h.Name] {
+                return nil, err
+            }
+            warnf("skipping %v", err)
+        }
+        if len(valid) == len(hosts) {
+            return valid, nil
+        }
+        hosts = valid
+    }
+}
+
+func checkHostVia(hosts []Host, h Host) error {
+    if len(h.Via) > 0 && HostKind(h) != KindSSH {
+        return fmt.Errorf("host %s: via only applies to ssh hosts", h.Name)
+    }
+    for _, hop := range h.Via {
+        if hop == h.Name {
+            return fmt.Errorf("host %s: cannot jump through itself", h.Name)
+        }
+        jump := FindHost(hosts, hop)
+        if jump == nil {
+            return fmt.Errorf("host %s: unknown jump host %q", h.Name, hop)
+        }
+        if HostKind(*jump) != KindSSH {
+            return fmt.Errorf("host %s: jump host %q is not an ssh host", h.Name, hop)
+        }
+    }
+    if _, err := JumpChain(hosts, h); err != nil {
+        return fmt.Errorf("host %s: %w", h.Name, err)
+    }
+    return nil
+}
+
+func JumpChain(hosts []Host, host Host) ([]Host, error) {
+    return jumpChain(hosts, host, []string{host.Name})
+}
+
+func jumpChain(hosts []Host, host Host, path []string) ([]Host, error) {
+    var chain []Host
+    for _, name := range host.Via {
+        for _, visited := range path {
+            if visited == name {
+                return nil, fmt.Errorf("jump host cycle: %s -> %s", strings.Join(path, " -> "), name)
+            }
+        }
+        hop := FindHost(hosts, name)
+        if hop == nil {
+            return nil, fmt.Errorf("host %s: unknown jump host %q", host.Name, name)
+        }
+        before, err := jumpChain(hosts, *hop, append(path[:len(path):len(path)], name))
+        if err != nil {
+            return nil, err
+        }
+        chain = append(chain, before...)
+        chain = append(chain, *hop)
+    }
+    return chain, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "sync"
+)
+
+var (
+    warnMu   sync.Mutex
+    warnOut  io.Writer = os.Stderr
+    warnSeen           = make(map[string]bool)
+)
+
+func SetWarnings(w io.Writer) {
+    warnMu.Lock()
+    defer warnMu.Unlock()
+    warnOut = w
+}
+
+func warnf(format string, args ...any) {
+    message := fmt.Sprintf(format, args...)
+    warnMu.Lock()
+    defer warnMu.Unlock()
+    if warnSeen[message] || warnOut == nil {
+        return
+    }
+    warnSeen[message] = true
+    fmt.Fprintf(warnOut, "Warning: %s\n", message)
+}
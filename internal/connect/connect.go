@@ -0,0 +1,168 @@
+package connect
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+const defaultControlPersist = "10m"
+
+type Options struct {
+    Forwards []string
+    Hosts    []config.Host
+}
+
+func Command(host config.Host, opts Options) ([]string, error) {
+    kind := config.HostKind(host)
+    if kind != config.KindSSH && len(opts.Forwards) > 0 {
+        return nil, fmt.Errorf("forwards only apply to ssh hosts, %s is a %s host", host.Name, kind)
+    }
+    switch kind {
+    case config.KindSSH:
+        return sshCommand(host, opts)
+    case config.KindLocal:
+        return localCommand(host), nil
+    case config.KindDocker:
+        return dockerCommand(host), nil
+    case config.KindKubectl:
+        return kubectlCommand(host), nil
+    }
+    return nil, fmt.Errorf("host %s has unknown kind %q", host.Name, host.Kind)
+}
+
+func sshCommand(host config.Host, opts Options) ([]string, error) {
+    shared, err := sshArgs(host, opts)
+    if err != nil {
+        return nil, err
+    }
+    var args []string
+    if host.Term != "" {
+        args = append(args, "env", "TERM="+host.Term)
+    }
+    args = append(args, "ssh", "-tt")
+    args = append(args, shared...)
+    args = append(args, envArgs(host)...)
+    for _, name := range opts.Forwards {
+        spec, ok := host.Forwards[name]
+        if !ok {
+            return nil, fmt.Errorf("host %s has no forward named %q", host.Name, name)
+        }
+        forward, err := config.ParseForward(spec)
+        if err != nil {
+            return nil, fmt.Errorf("forward %s: %w", name, err)
+        }
+        args = append(args, forward.Args()...)
+    }
+    return append(args, "--", host.Target), nil
+}
+
+func ControlCommand(host config.Host, operation string, opts Options) ([]string, error) {
+    if !host.Multiplex || config.HostKind(host) != config.KindSSH {
+        return nil, fmt.Errorf("connection sharing is not enabled for %s", host.Name)
+    }
+    shared, err := sshArgs(host, opts)
+    if err != nil {
+        return nil, err
+    }
+    args := append([]string{"ssh", "-O", operation}, shared...)
+    return append(args, "--", host.Target), nil
+}
+
+func sshArgs(host config.Host, opts Options) ([]string, error) {
+    var args []string
+    keys := make([]string, 0, len(host.Options))
+    for key := range host.Options {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        args = append(args, "-o", key+"="+host.Options[key])
+    }
+    if host.Multiplex {
+        dir, err := controlDir()
+        if err != nil {
+            return nil, err
+        }
+        persist := host.ControlPersist
+        if persist == "" {
+            persist = defaultControlPersist
+        }
+        args = append(args,
+            "-o", "ControlMaster=auto",
+            "-o", "ControlPath="+filepath.Join(dir, "%C"),
+            "-o", "ControlPersist="+persist,
+        )
+    }
+    if len(host.Via) > 0 {
+        chain, err := config.JumpChain(opts.Hosts, host)
+        if err != nil {
+            return nil, err
+        }
+        hops := make([]string, 0, len(chain))
+        for _, hop := range chain {
+            hops = append(hops, jumpSpec(hop))
+        }
+        args = append(args, "-J", strings.Join(hops, ","))
+    }
+    return args, nil
+}
+
+func envArgs(host config.Host) []string {
+    var args []string
+    for _, pattern := range host.SendEnv {
+        args = append(args, "-o", "SendEnv="+pattern)
+    }
+    if len(host.Env) == 0 {
+        return args
+    }
+    keys := make([]string, 0, len(host.Env))
+    for key := range host.Env {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    pairs := make([]string, 0, len(keys))
+    for _, key := range keys {
+        pair := key + "=" + host.Env[key]
+        if strings.ContainsAny(pair, " \t\"'\\") {
+            pair = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(pair) + `"`
+        }
+        pairs = append(pairs, pair)
+    }
+    return append(args, "-o", "SetEnv="+strings.Join(pairs, " "))
+}
+
+func ScreenOptions(host config.Host) session.ScreenOptions {
+    var opts session.ScreenOptions
+    opts.UTF8, _ = config.ParseSwitch(host.Screen["utf8"])
+    opts.Scrollback, _ = strconv.Atoi(host.Screen["scrollback"])
+    opts.Title = host.Screen["title"]
+    return opts
+}
+
+func controlDir() (string, error) {
+    state, err := config.StateDir()
+    if err != nil {
+        return "", err
+    }
+    dir := filepath.Join(state, "control")
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return "", err
+    }
+    return dir, nil
+}
+
+func jumpSpec(hop config.Host) string {
+    for key, value := range hop.Options {
+        if strings.EqualFold(key, "Port") {
+            return hop.Target + ":" + value
+        }
+    }
+    return hop.Target
+}
@@ -0,0 +1,291 @@
+package connect
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+func TestCommand(t *testing.T) {
+    state := t.TempDir()
+    t.Setenv("SCHH_STATE_DIR", state)
+    t.Setenv("SHELL", "/bin/zsh")
+
+    bastion := config.Host{Name: "bastion", Target: "jump@bastion.example", Options: map[string]string{"Port": "2222"}}
+    inner := config.Host{Name: "inner", Target: "inner.example", Via: []string{"bastion"}}
+    hosts := []config.Host{bastion, inner}
+
+    tests := []struct {
+        name string
+        host config.Host
+        opts Options
+        want []string
+    }{
+        {
+            name: "ssh",
+            host: config.Host{Name: "prod", Target: "deploy@prod.example"},
+            want: []string{"ssh", "-tt", "--", "deploy@prod.example"},
+        },
+        {
+            name: "ssh options and term",
+            host: config.Host{
+                Name:    "prod",
+                Target:  "prod.example",
+                Term:    "xterm-256color",
+                Options: map[string]string{"User": "deploy", "Port": "2200"},
+            },
+            want: []string{"env", "TERM=xterm-256color", "ssh", "-tt", "-o", "Port=2200", "-o", "User=deploy", "--", "prod.example"},
+        },
+        {
+            name: "ssh env",
+            host: config.Host{
+                Name:    "prod",
+                Target:  "prod.example",
+                SendEnv: []string{"LC_*"},
+                Env:     map[string]string{"APP": "api", "GREETING": `say "hi" there`},
+            },
+            want: []string{"ssh", "-tt", "-o", "SendEnv=LC_*", "-o", `SetEnv=APP=api "GREETING=say \"hi\" there"`, "--", "prod.example"},
+        },
+        {
+            name: "ssh via",
+            host: config.Host{Name: "db", Target: "db.internal", Via: []string{"inner"}},
+            opts: Options{Hosts: hosts},
+            want: []string{"ssh", "-tt", "-J", "jump@bastion.example:2222,inner.example", "--", "db.internal"},
+        },
+        {
+            name: "ssh forwards",
+            host: config.Host{
+                Name:     "prod",
+                Target:   "prod.example",
+                Forwards: map[string]string{"web": "L:8080:localhost:80", "socks": "D:1080"},
+            },
+            opts: Options{Forwards: []string{"web", "socks"}},
+            want: []string{"ssh", "-tt", "-L", "8080:localhost:80", "-D", "1080", "--", "prod.example"},
+        },
+        {
+            name: "ssh multiplex",
+            host: config.Host{Name: "prod", Target: "prod.example", Multiplex: true, ControlPersist: "1h"},
+            want: []string{
+                "ssh", "-tt",
+                "-o", "ControlMaster=auto",
+                "-o", "ControlPath=" + filepath.Join(state, "control", "%C"),
+                "-o", "ControlPersist=1h",
+                "--", "prod.example",
+            },
+        },
+        {
+            name: "ssh target starting with a dash",
+            host: config.Host{Name: "odd", Target: "-oProxyCommand=x"},
+            want: []string{"ssh", "-tt", "--", "-oProxyCommand=x"},
+        },
+        {
+            name: "local",
+            host: config.Host{Name: "here", Target: "here", Kind: config.KindLocal},
+            want: []string{"/bin/zsh"},
+        },
+        {
+            name: "local env and shell",
+            host: config.Host{Name: "here", Target: "here", Kind: config.KindLocal, Shell: "/bin/bash", Term: "screen", Env: map[string]string{"A": "1"}},
+            want: []string{"env", "TERM=screen", "A=1", "/bin/bash"},
+        },
+        {
+            name: "docker",
+            host: config.Host{Name: "app", Target: "app", Kind: config.KindDocker},
+            want: []string{"docker", "exec", "-it", "app", "sh"},
+        },
+        {
+            name: "docker container context env",
+            host: config.Host{
+                Name:      "app",
+                Target:    "app",
+                Kind:      config.KindDocker,
+                Container: "app-1",
+                Context:   "remote",
+                Shell:     "bash",
+                Env:       map[string]string{"B": "2", "A": "1"},
+            },
+            want: []string{"docker", "--context", "remote", "exec", "-it", "-e", "A=1", "-e", "B=2", "app-1", "bash"},
+        },
+        {
+            name: "kubectl",
+            host: config.Host{Name: "api", Target: "api-0", Kind: config.KindKubectl},
+            want: []string{"kubectl", "exec", "-it", "api-0", "--", "sh"},
+        },
+        {
+            name: "kubectl pod namespace container env",
+            host: config.Host{
+                Name:      "api",
+                Target:    "api",
+                Kind:      config.KindKubectl,
+                Pod:       "api-7d9",
+                Namespace: "prod",
+                Context:   "eu",
+                Container: "app",
+                Env:       map[string]string{"A": "1"},
+            },
+            want: []string{"kubectl", "--context", "eu", "--namespace", "prod", "exec", "-it", "api-7d9", "--container", "app", "--", "env", "A=1", "sh"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := Command(tt.host, tt.opts)
+            if err != nil {
+                t.Fatalf("Command: %v", err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Command =\n  %q\nwant\n  %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestCommandErrors(t *testing.T) {
+    hosts := []config.Host{
+        {Name: "a", Target: "a", Via: []string{"b"}},
+        {Name: "b", Target: "b", Via: []string{"a"}},
+    }
+    tests := []struct {
+        name string
+        host config.Host
+        opts Options
+        want string
+    }{
+        {
+            name: "forwards on docker",
+            host: config.Host{Name: "app", Target: "app", Kind: config.KindDocker},
+            opts: Options{Forwards: []string{"web"}},
+            want: "forwards only apply to ssh hosts",
+        },
+        {
+            name: "unknown forward",
+            host: config.Host{Name: "prod", Target: "prod"},
+            opts: Options{Forwards: []string{"web"}},
+            want: `no forward named "web"`,
+        },
+        {
+            name: "unknown jump host",
+            host: config.Host{Name: "prod", Target: "prod", Via: []string{"missing"}},
+            want: `unknown jump host "missing"`,
+        },
+        {
+            name: "jump cycle",
+            host: hosts[0],
+            opts: Options{Hosts: hosts},
+            want: "jump host cycle",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := Command(tt.host, tt.opts)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Fatalf("Command error = %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestControlCommand(t *testing.T) {
+    state := t.TempDir()
+    t.Setenv("SCHH_STATE_DIR", state)
+
+    host := config.Host{Name: "prod", Target: "prod.example", Multiplex: true}
+    got, err := ControlCommand(host, "exit", Options{})
+    if err != nil {
+        t.Fatalf("ControlCommand: %v", err)
+    }
+    want := []string{
+        "ssh", "-O", "exit",
+        "-o", "ControlMaster=auto",
+        "-o", "ControlPath=" + filepath.Join(state, "control", "%C"),
+        "-o", "ControlPersist=" + defaultControlPersist,
+        "--", "prod.example",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("ControlCommand =\n  %q\nwant\n  %q", got, want)
+    }
+
+    host.Multiplex = false
+    if _, err := ControlCommand(host, "exit", Options{}); err == nil {
+        t.Error("ControlCommand without multiplex: expected an error")
+    }
+}
+
+func TestStartContainerSession(t *testing.T) {
+    bin := t.TempDir()
+    argv := filepath.Join(t.TempDir(), "argv")
+    stubs := map[string]string{
+        "screen":  "#!/bin/sh\nshift 2\nif [ \"$1\" = -c ]; then shift 2; fi\nexec \"$@\"\n",
+        "tmux":    "#!/bin/sh\nwhile [ \"$1\" != -- ]; do shift; done\nshift\nexec \"$@\"\n",
+        "docker":  "#!/bin/sh\nprintf '%s\\n' docker \"$@\" > \"$STUB_ARGV\"\n",
+        "kubectl": "#!/bin/sh\nprintf '%s\\n' kubectl \"$@\" > \"$STUB_ARGV\"\n",
+    }
+    for name, script := range stubs {
+        if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+    t.Setenv("STUB_ARGV", argv)
+    t.Cleanup(func() { session.SetBackend("screen") })
+
+    tests := []struct {
+        name string
+        host config.Host
+        want []string
+    }{
+        {
+            name: "docker",
+            host: config.Host{
+                Name:    "app",
+                Target:  "app",
+                Kind:    config.KindDocker,
+                Context: "remote",
+                Shell:   "bash",
+                Env:     map[string]string{"GREETING": "hello world"},
+            },
+            want: []string{"docker", "--context", "remote", "exec", "-it", "-e", "GREETING=hello world", "app", "bash"},
+        },
+        {
+            name: "kubectl",
+            host: config.Host{
+                Name:      "api",
+                Target:    "api-0",
+                Kind:      config.KindKubectl,
+                Namespace: "prod",
+                Container: "app",
+                Term:      "xterm",
+            },
+            want: []string{"kubectl", "--namespace", "prod", "exec", "-it", "api-0", "--container", "app", "--", "env", "TERM=xterm", "sh"},
+        },
+    }
+    for _, backend := range session.Backends {
+        if err := session.SetBackend(backend); err != nil {
+            t.Fatal(err)
+        }
+        for _, tt := range tests {
+            t.Run(backend+"/"+tt.name, func(t *testing.T) {
+                os.Remove(argv)
+                command, err := Command(tt.host, Options{})
+                if err != nil {
+                    t.Fatalf("Command: %v", err)
+                }
+                if err := session.StartDetachedSession("schh_"+tt.host.Name+"_main", command, ""); err != nil {
+                    t.Fatalf("StartDetachedSession: %v", err)
+                }
+                data, err := os.ReadFile(argv)
+                if err != nil {
+                    t.Fatalf("%s was not run: %v", tt.want[0], err)
+                }
+                got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+                if !reflect.DeepEqual(got, tt.want) {
+                    t.Errorf("%s ran\n  %q\nwant\n  %q", tt.want[0], got, tt.want)
+                }
+            })
+        }
+    }
+}
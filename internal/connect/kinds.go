@@ -0,0 +1,89 @@
+package connect
+
+import (
+    "os"
+    "sort"
+
+    "schh/internal/config"
+)
+
+const defaultContainerShell = "sh"
+
+func localCommand(host config.Host) []string {
+    shell := host.Shell
+    if shell == "" {
+        shell = os.Getenv("SHELL")
+    }
+    if shell == "" {
+        shell = "/bin/sh"
+    }
+    env := envPairs(host)
+    if len(env) == 0 {
+        return []string{shell}
+    }
+    args := append([]string{"env"}, env...)
+    return append(args, shell)
+}
+
+func dockerCommand(host config.Host) []string {
+    args := []string{"docker"}
+    if host.Context != "" {
+        args = append(args, "--context", host.Context)
+    }
+    args = append(args, "exec", "-it")
+    for _, pair := range envPairs(host) {
+        args = append(args, "-e", pair)
+    }
+    container := host.Container
+    if container == "" {
+        container = host.Target
+    }
+    return append(args, container, containerShell(host))
+}
+
+func kubectlCommand(host config.Host) []string {
+    args := []string{"kubectl"}
+    if host.Context != "" {
+        args = append(args, "--context", host.Context)
+    }
+    if host.Namespace != "" {
+        args = append(args, "--namespace", host.Namespace)
+    }
+    pod := host.Pod
+    if pod == "" {
+        pod = host.Target
+    }
+    args = append(args, "exec", "-it", pod)
+    if host.Container != "" {
+        args = append(args, "--container", host.Container)
+    }
+    args = append(args, "--")
+    if env := envPairs(host); len(env) > 0 {
+        args = append(args, "env")
+        args = append(args, env...)
+    }
+    return append(args, containerShell(host))
+}
+
+func containerShell(host config.Host) string {
+    if host.Shell != "" {
+        return host.Shell
+    }
+    return defaultContainerShell
+}
+
+func envPairs(host config.Host) []string {
+    keys := make([]string, 0, len(host.Env))
+    for key := range host.Env {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    var pairs []string
+    if host.Term != "" {
+        pairs = append(pairs, "TERM="+host.Term)
+    }
+    for _, key := range keys {
+        pairs = append(pairs, key+"="+host.Env[key])
+    }
+    return pairs
+}
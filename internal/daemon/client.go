@@ -0,0 +1,167 @@
+package daemon
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+const (
+    requestTimeout = 2 * time.Second
+    actionTimeout  = 30 * time.Second
+)
+
+var (
+    ErrNotRunning      = errors.New("daemon is not running")
+    ErrSessionExists   = errors.New("session already exists")
+    ErrSessionNotFound = errors.New("session is not running")
+)
+
+type Client struct {
+    http *http.Client
+}
+
+func Connect() (*Client, error) {
+    path, err := SocketPath()
+    if err != nil {
+        return nil, err
+    }
+    if _, err := os.Stat(path); err != nil {
+        return nil, ErrNotRunning
+    }
+    conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+    if err != nil {
+        return nil, ErrNotRunning
+    }
+    conn.Close()
+    transport := &http.Transport{
+        DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+            var d net.Dialer
+            return d.DialContext(ctx, "unix", path)
+        },
+    }
+    return &Client{http: &http.Client{Transport: transport}}, nil
+}
+
+func (c *Client) Ping() (PingResponse, error) {
+    var ping PingResponse
+    err := c.do(http.MethodGet, "/ping", nil, &ping)
+    return ping, err
+}
+
+func (c *Client) Hosts() ([]config.Host, error) {
+    var hosts []config.Host
+    err := c.do(http.MethodGet, "/hosts", nil, &hosts)
+    return hosts, err
+}
+
+func (c *Client) Sessions() ([]session.Info, error) {
+    var sessions []session.Info
+    err := c.do(http.MethodGet, "/sessions", nil, &sessions)
+    return sessions, err
+}
+
+func (c *Client) SessionsForHost(host string) ([]session.Info, error) {
+    var sessions []session.Info
+    err := c.do(http.MethodGet, "/sessions?host="+url.QueryEscape(host), nil, &sessions)
+    return sessions, err
+}
+
+func (c *Client) Start(host, label string) (string, error) {
+    var resp StartResponse
+    err := c.do(http.MethodPost, "/start", StartRequest{Host: host, Label: label}, &resp)
+    return resp.SessionID, err
+}
+
+func (c *Client) Kill(host, label string) error {
+    return c.do(http.MethodPost, "/kill", KillRequest{Host: host, Label: label}, nil)
+}
+
+func (c *Client) Events(ctx context.Context, handle func(session.Event)) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://schh/events", nil)
+    if err != nil {
+        return err
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return responseError(resp)
+    }
+    scanner := bufio.NewScanner(resp.Body)
+    for scanner.Scan() {
+        var event session.Event
+        if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+            return err
+        }
+        handle(event)
+    }
+    if ctx.Err() != nil {
+        return nil
+    }
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    return ErrNotRunning
+}
+
+func (c *Client) do(method, path string, body, out any) error {
+    var payload bytes.Buffer
+    if body != nil {
+        if err := json.NewEncoder(&payload).Encode(body); err != nil {
+            return err
+        }
+    }
+    timeout := requestTimeout
+    if method != http.MethodGet {
+        timeout = actionTimeout
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, method, "http://schh"+path, &payload)
+    if err != nil {
+        return err
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        return responseError(resp)
+    }
+    if out == nil {
+        return nil
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func responseError(resp *http.Response) error {
+    var body struct {
+        Error string `json:"error"`
+    }
+    json.NewDecoder(resp.Body).Decode(&body)
+    if body.Error == "" {
+        body.Error = resp.Status
+    }
+    if resp.StatusCode == http.StatusConflict {
+        return fmt.Errorf("%w: %s", ErrSessionExists, body.Error)
+    }
+    return errors.New(body.Error)
+}
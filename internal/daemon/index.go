@@ -0,0 +1,84 @@
+package daemon
+
+import (
+    "fmt"
+    "sync"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+type Index struct {
+    mu       sync.Mutex
+    sessions []session.Info
+    updated  time.Time
+    subs     map[chan session.Event]struct{}
+}
+
+func NewIndex() *Index {
+    return &Index{subs: make(map[chan session.Event]struct{})}
+}
+
+func (ix *Index) Refresh() error {
+    sessions, err := session.ScanSessions()
+    if err != nil {
+        return err
+    }
+    if sessions == nil {
+        sessions = []session.Info{}
+    }
+    now := time.Now()
+
+    ix.mu.Lock()
+    defer ix.mu.Unlock()
+    var events []session.Event
+    if !ix.updated.IsZero() {
+        events = session.DiffSessions(ix.sessions, sessions, now)
+    }
+    attached := attachedNames(ix.sessions, sessions)
+    ix.sessions = sessions
+    ix.updated = now
+    for _, event := range events {
+        for ch := range ix.subs {
+            select {
+            case ch <- event:
+            default:
+            }
+        }
+    }
+    if err := config.MarkAttached(attached, now); err != nil {
+        return fmt.Errorf("recording attached sessions: %w", err)
+    }
+    return nil
+}
+
+func attachedNames(before, after []session.Info) []string {
+    var names []string
+    for _, list := range [][]session.Info{before, after} {
+        for _, s := range list {
+            if s.Attached {
+                names = append(names, s.Name)
+            }
+        }
+    }
+    return names
+}
+
+func (ix *Index) Sessions() []session.Info {
+    ix.mu.Lock()
+    defer ix.mu.Unlock()
+    return append([]session.Info{}, ix.sessions...)
+}
+
+func (ix *Index) Subscribe() (<-chan session.Event, func()) {
+    ch := make(chan session.Event, 64)
+    ix.mu.Lock()
+    ix.subs[ch] = struct{}{}
+    ix.mu.Unlock()
+    return ch, func() {
+        ix.mu.Lock()
+        delete(ix.subs, ch)
+        ix.mu.Unlock()
+    }
+}
@@ -0,0 +1,243 @@
+package daemon
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "time"
+
+    "schh/internal/config"
+    "schh/internal/session"
+)
+
+var ErrRunning = errors.New("daemon is already running")
+
+type PingResponse struct {
+    PID     int    `json:"pid"`
+    Backend string `json:"backend"`
+}
+
+type StartRequest struct {
+    Host  string `json:"host"`
+    Label string `json:"label"`
+}
+
+type StartResponse struct {
+    SessionID string `json:"session_id"`
+}
+
+type KillRequest struct {
+    Host  string `json:"host"`
+    Label string `json:"label"`
+}
+
+type Server struct {
+    Index  *Index
+    Start  func(host, label string) (string, error)
+    Kill   func(host, label string) error
+    Logger *log.Logger
+}
+
+func SocketPath() (string, error) {
+    dir, err := config.StateDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, "daemon.sock"), nil
+}
+
+func (s *Server) Serve(ctx context.Context) error {
+    path, err := SocketPath()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+    if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+        conn.Close()
+        return fmt.Errorf("%w at %s", ErrRunning, path)
+    }
+    os.Remove(path)
+
+    listener, err := net.Listen("unix", path)
+    if err != nil {
+        return err
+    }
+    defer os.Remove(path)
+    if err := os.Chmod(path, 0o600); err != nil {
+        listener.Close()
+        return err
+    }
+
+    if err := s.Index.Refresh(); err != nil {
+        s.logf("reading sessions: %v", err)
+    }
+    go s.Index.Watch(ctx, s.logf)
+
+    server := &http.Server{Handler: s.handler()}
+    go func() {
+        <-ctx.Done()
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+        defer cancel()
+        server.Shutdown(shutdownCtx)
+    }()
+    s.logf("listening on %s", path)
+    if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    return nil
+}
+
+func (s *Server) handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+        writeJSON(w, http.StatusOK, PingResponse{PID: os.Getpid(), Backend: session.BackendName()})
+    })
+    mux.HandleFunc("/hosts", s.handleHosts)
+    mux.HandleFunc("/sessions", s.handleSessions)
+    mux.HandleFunc("/start", s.handleStart)
+    mux.HandleFunc("/kill", s.handleKill)
+    mux.HandleFunc("/events", s.handleEvents)
+    return mux
+}
+
+func (s *Server) handleHosts(w http.ResponseWriter, r *http.Request) {
+    if !allowMethod(w, r, http.MethodGet) {
+        return
+    }
+    hosts, err := config.LoadHosts()
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err)
+        return
+    }
+    if hosts == nil {
+        hosts = []config.Host{}
+    }
+    writeJSON(w, http.StatusOK, hosts)
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+    if !allowMethod(w, r, http.MethodGet) {
+        return
+    }
+    sessions := s.Index.Sessions()
+    if host := r.URL.Query().Get("host"); host != "" {
+        token := session.SanitizeToken(host)
+        filtered := []session.Info{}
+        for _, info := range sessions {
+            if info.HostToken == token {
+                filtered = append(filtered, info)
+            }
+        }
+        sessions = filtered
+    }
+    writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+    if !allowMethod(w, r, http.MethodPost) {
+        return
+    }
+    var req StartRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" {
+        writeError(w, http.StatusBadRequest, errors.New("expected a JSON body with a host and an optional label"))
+        return
+    }
+    id, err := s.Start(req.Host, req.Label)
+    if err != nil {
+        writeError(w, statusFor(err), err)
+        return
+    }
+    s.Index.Refresh()
+    writeJSON(w, http.StatusCreated, StartResponse{SessionID: id})
+}
+
+func (s *Server) handleKill(w http.ResponseWriter, r *http.Request) {
+    if !allowMethod(w, r, http.MethodPost) {
+        return
+    }
+    var req KillRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" || req.Label == "" {
+        writeError(w, http.StatusBadRequest, errors.New("expected a JSON body with a host and a label"))
+        return
+    }
+    if err := s.Kill(req.Host, req.Label); err != nil {
+        writeError(w, statusFor(err), err)
+        return
+    }
+    s.Index.Refresh()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+    if !allowMethod(w, r, http.MethodGet) {
+        return
+    }
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+        return
+    }
+    events, cancel := s.Index.Subscribe()
+    defer cancel()
+
+    w.Header().Set("Content-Type", "application/x-ndjson")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+    encoder := json.NewEncoder(w)
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case event := <-events:
+            if err := encoder.Encode(event); err != nil {
+                return
+            }
+            flusher.Flush()
+        }
+    }
+}
+
+func (s *Server) logf(format string, args ...any) {
+    if s.Logger != nil {
+        s.Logger.Printf(format, args...)
+    }
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+    if r.Method == method {
+        return true
+    }
+    w.Header().Set("Allow", method)
+    writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("use %s", method))
+    return false
+}
+
+func statusFor(err error) int {
+    switch {
+    case errors.Is(err, config.ErrHostNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, ErrSessionExists):
+        return http.StatusConflict
+    case errors.Is(err, ErrSessionNotFound):
+        return http.StatusNotFound
+    }
+    return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+    writeJSON(w, status, map[string]string{"error": err.Error()})
+}
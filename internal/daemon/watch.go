@@ -0,0 +1,69 @@
+package daemon
+
+import (
+    "context"
+    "errors"
+    "os"
+    "time"
+
+    "schh/internal/session"
+)
+
+const (
+    pollInterval   = 2 * time.Second
+    resyncInterval = 30 * time.Second
+)
+
+var (
+    errWatchEnded       = errors.New("watched directory was removed")
+    errWatchUnsupported = errors.New("directory watching is not supported on this platform")
+)
+
+func (ix *Index) Watch(ctx context.Context, logf func(string, ...any)) {
+    for ctx.Err() == nil {
+        dir, err := session.SocketDir()
+        if err == nil {
+            _, err = os.Stat(dir)
+        }
+        if err == nil {
+            watchCtx, cancel := context.WithCancel(ctx)
+            go ix.resync(watchCtx, resyncInterval, logf)
+            err = watchDir(watchCtx, dir, func() { ix.refresh(logf) })
+            cancel()
+            if err == nil {
+                return
+            }
+            if !errors.Is(err, errWatchEnded) {
+                logf("watching %s failed, polling instead: %v", dir, err)
+                ix.resync(ctx, pollInterval, logf)
+                return
+            }
+            ix.refresh(logf)
+            continue
+        }
+        select {
+        case <-ctx.Done():
+        case <-time.After(pollInterval):
+            ix.refresh(logf)
+        }
+    }
+}
+
+func (ix *Index) resync(ctx context.Context, interval time.Duration, logf func(string, ...any)) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            ix.refresh(logf)
+        }
+    }
+}
+
+func (ix *Index) refresh(logf func(string, ...any)) {
+    if err := ix.Refresh(); err != nil {
+        logf("refreshing sessions: %v", err)
+    }
+}
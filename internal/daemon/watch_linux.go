@@ -0,0 +1,52 @@
+//go:build linux
+
+package daemon
+
+import (
+    "context"
+    "os"
+    "syscall"
+    "unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_ATTRIB |
+    syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+func watchDir(ctx context.Context, dir string, changed func()) error {
+    fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+    if err != nil {
+        return err
+    }
+    if _, err := syscall.InotifyAddWatch(fd, dir, inotifyMask); err != nil {
+        syscall.Close(fd)
+        return err
+    }
+    file := os.NewFile(uintptr(fd), "inotify")
+    go func() {
+        <-ctx.Done()
+        file.Close()
+    }()
+
+    buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+    for {
+        n, err := file.Read(buf)
+        if err != nil {
+            if ctx.Err() != nil {
+                return nil
+            }
+            return err
+        }
+        selfDeleted := false
+        for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+            event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+            if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_IGNORED) != 0 {
+                selfDeleted = true
+            }
+            offset += syscall.SizeofInotifyEvent + int(event.Len)
+        }
+        changed()
+        if selfDeleted {
+            return errWatchEnded
+        }
+    }
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package daemon
+
+import "context"
+
+func watchDir(ctx context.Context, dir string, changed func()) error {
+    return errWatchUnsupported
+}
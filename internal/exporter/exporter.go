@@ -0,0 +1,115 @@
+package exporter
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+
+    "schh/internal/config"
+)
+
+const TagsComment = "# schh-tags:"
+
+var Formats = []string{"ssh-config", "json", "csv"}
+
+func Write(w io.Writer, format string, hosts []config.Host) error {
+    switch format {
+    case "ssh-config":
+        return writeSSHConfig(w, hosts)
+    case "json":
+        return writeJSON(w, hosts)
+    case "csv":
+        return writeCSV(w, hosts)
+    default:
+        return fmt.Errorf("unknown export format %q", format)
+    }
+}
+
+func writeSSHConfig(w io.Writer, hosts []config.Host) error {
+    writer := bufio.NewWriter(w)
+    fmt.Fprintln(writer, "# Generated by schh. Edit the schh hosts file instead of this file.")
+    for _, h := range hosts {
+        if config.HostKind(h) != config.KindSSH {
+            continue
+        }
+        user, hostName := SplitTarget(h.Target)
+        fmt.Fprintf(writer, "\nHost %s\n", h.Name)
+        fmt.Fprintf(writer, "    HostName %s\n", hostName)
+        if user != "" {
+            fmt.Fprintf(writer, "    User %s\n", user)
+        }
+        if len(h.Via) > 0 {
+            fmt.Fprintf(writer, "    ProxyJump %s\n", strings.Join(h.Via, ","))
+        }
+        for _, key := range sortedKeys(h.Options) {
+            fmt.Fprintf(writer, "    %s %s\n", key, quoteSSHValue(h.Options[key]))
+        }
+        if len(h.SendEnv) > 0 {
+            fmt.Fprintf(writer, "    SendEnv %s\n", strings.Join(h.SendEnv, " "))
+        }
+        if len(h.Env) > 0 {
+            pairs := make([]string, 0, len(h.Env))
+            for _, key := range sortedKeys(h.Env) {
+                pairs = append(pairs, quoteSSHValue(key+"="+h.Env[key]))
+            }
+            fmt.Fprintf(writer, "    SetEnv %s\n", strings.Join(pairs, " "))
+        }
+        if len(h.Tags) > 0 {
+            fmt.Fprintf(writer, "    %s %s\n", TagsComment, strings.Join(h.Tags, ","))
+        }
+    }
+    return writer.Flush()
+}
+
+func writeJSON(w io.Writer, hosts []config.Host) error {
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(hosts)
+}
+
+func writeCSV(w io.Writer, hosts []config.Host) error {
+    writer := csv.NewWriter(w)
+    if err := writer.Write([]string{"name", "target", "tags", "options", "via"}); err != nil {
+        return err
+    }
+    for _, h := range hosts {
+        options := make([]string, 0, len(h.Options))
+        for _, key := range sortedKeys(h.Options) {
+            options = append(options, key+"="+h.Options[key])
+        }
+        record := []string{h.Name, h.Target, strings.Join(h.Tags, ";"), strings.Join(options, ";"), strings.Join(h.Via, ";")}
+        if err := writer.Write(record); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+func SplitTarget(target string) (string, string) {
+    if idx := strings.LastIndex(target, "@"); idx >= 0 {
+        return target[:idx], target[idx+1:]
+    }
+    return "", target
+}
+
+func quoteSSHValue(value string) string {
+    if strings.ContainsAny(value, " \t\"'") {
+        replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+        return `"` + replacer.Replace(value) + `"`
+    }
+    return value
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
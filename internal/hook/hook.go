@@ -0,0 +1,52 @@
+package hook
+
+import (
+    "fmt"
+    "os"
+    "os/exec"
+)
+
+const (
+    PreStart   = "pre-start"
+    PreAttach  = "pre-attach"
+    PostDetach = "post-detach"
+    OnKill     = "on-kill"
+)
+
+var Events = []string{PreStart, PreAttach, PostDetach, OnKill}
+
+type Context struct {
+    Host      string
+    Target    string
+    Label     string
+    SessionID string
+}
+
+func IsEvent(name string) bool {
+    for _, event := range Events {
+        if event == name {
+            return true
+        }
+    }
+    return false
+}
+
+func Run(event string, commands []string, ctx Context) error {
+    for _, command := range commands {
+        cmd := exec.Command(command)
+        cmd.Env = append(os.Environ(),
+            "SCHH_EVENT="+event,
+            "SCHH_HOST="+ctx.Host,
+            "SCHH_TARGET="+ctx.Target,
+            "SCHH_LABEL="+ctx.Label,
+            "SCHH_SESSION_ID="+ctx.SessionID,
+        )
+        cmd.Stdin = os.Stdin
+        cmd.Stdout = os.Stderr
+        cmd.Stderr = os.Stderr
+        if err := cmd.Run(); err != nil {
+            return fmt.Errorf("%s hook %s: %w", event, command, err)
+        }
+    }
+    return nil
+}
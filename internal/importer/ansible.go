@@ -0,0 +1,458 @@
+package importer
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "schh/internal/config"
+)
+
+type ansibleGroup struct {
+    name     string
+    hosts    []string
+    children []string
+    vars     map[string]string
+}
+
+type ansibleInventory struct {
+    groups   map[string]*ansibleGroup
+    hosts    []string
+    hostVars map[string]map[string]string
+}
+
+func Ansible(path, prefix string) ([]config.Host, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var inv *ansibleInventory
+    if isYAMLInventory(path, data) {
+        root, err := parseYAML(bytes.NewReader(data))
+        if err != nil {
+            return nil, err
+        }
+        inv, err = ansibleFromYAML(root)
+        if err != nil {
+            return nil, err
+        }
+    } else {
+        inv, err = parseAnsibleINI(bytes.NewReader(data))
+        if err != nil {
+            return nil, err
+        }
+    }
+    return inv.toHosts(prefix)
+}
+
+func isYAMLInventory(path string, data []byte) bool {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yml", ".yaml":
+        return true
+    case ".ini", ".cfg":
+        return false
+    }
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+            continue
+        }
+        return line == "---" || (strings.HasSuffix(line, ":") && !strings.HasPrefix(line, "["))
+    }
+    return false
+}
+
+func newAnsibleInventory() *ansibleInventory {
+    return &ansibleInventory{
+        groups:   make(map[string]*ansibleGroup),
+        hostVars: make(map[string]map[string]string),
+    }
+}
+
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+    g, ok := inv.groups[name]
+    if !ok {
+        g = &ansibleGroup{name: name, vars: make(map[string]string)}
+        inv.groups[name] = g
+    }
+    return g
+}
+
+func (inv *ansibleInventory) addHost(groupName, host string, vars map[string]string) {
+    if _, ok := inv.hostVars[host]; !ok {
+        inv.hostVars[host] = make(map[string]string)
+        inv.hosts = append(inv.hosts, host)
+    }
+    for key, value := range vars {
+        inv.hostVars[host][key] = value
+    }
+    g := inv.group(groupName)
+    for _, existing := range g.hosts {
+        if existing == host {
+            return
+        }
+    }
+    g.hosts = append(g.hosts, host)
+}
+
+func (inv *ansibleInventory) addChild(parent, child string) {
+    g := inv.group(parent)
+    inv.group(child)
+    for _, existing := range g.children {
+        if existing == child {
+            return
+        }
+    }
+    g.children = append(g.children, child)
+}
+
+func (inv *ansibleInventory) toHosts(prefix string) ([]config.Host, error) {
+    parents := make(map[string][]string)
+    for _, g := range inv.groups {
+        for _, child := range g.children {
+            parents[child] = append(parents[child], g.name)
+        }
+    }
+    depths := make(map[string]int)
+    var depth func(name string, visiting map[string]bool) (int, error)
+    depth = func(name string, visiting map[string]bool) (int, error) {
+        if d, ok := depths[name]; ok {
+            return d, nil
+        }
+        if visiting[name] {
+            return 0, fmt.Errorf("group %q is its own ancestor", name)
+        }
+        visiting[name] = true
+        d := 0
+        for _, parent := range parents[name] {
+            pd, err := depth(parent, visiting)
+            if err != nil {
+                return 0, err
+            }
+            d = max(d, pd+1)
+        }
+        delete(visiting, name)
+        depths[name] = d
+        return d, nil
+    }
+
+    hosts := make([]config.Host, 0, len(inv.hosts))
+    for _, name := range inv.hosts {
+        closure := make(map[string]bool)
+        queue := []string{}
+        for _, g := range inv.groups {
+            for _, member := range g.hosts {
+                if member == name {
+                    queue = append(queue, g.name)
+                }
+            }
+        }
+        for len(queue) > 0 {
+            current := queue[0]
+            queue = queue[1:]
+            if closure[current] {
+                continue
+            }
+            closure[current] = true
+            queue = append(queue, parents[current]...)
+        }
+
+        groups := make([]string, 0, len(closure))
+        for g := range closure {
+            if _, err := depth(g, map[string]bool{}); err != nil {
+                return nil, err
+            }
+            groups = append(groups, g)
+        }
+        sort.Slice(groups, func(i, j int) bool {
+            if depths[groups[i]] != depths[groups[j]] {
+                return depths[groups[i]] < depths[groups[j]]
+            }
+            return groups[i] < groups[j]
+        })
+
+        vars := make(map[string]string)
+        if all, ok := inv.groups["all"]; ok {
+            for key, value := range all.vars {
+                vars[key] = value
+            }
+        }
+        var tags []string
+        for _, g := range groups {
+            for key, value := range inv.groups[g].vars {
+                vars[key] = value
+            }
+            if g != "all" && g != "ungrouped" {
+                tags = append(tags, g)
+            }
+        }
+        for key, value := range inv.hostVars[name] {
+            vars[key] = value
+        }
+        hosts = append(hosts, ansibleHost(prefix+name, name, vars, tags))
+    }
+    return hosts, nil
+}
+
+func ansibleHost(name, inventoryName string, vars map[string]string, tags []string) config.Host {
+    address := firstVar(vars, "ansible_host", "ansible_ssh_host")
+    if address == "" {
+        address = inventoryName
+    }
+    target := address
+    if user := firstVar(vars, "ansible_user", "ansible_ssh_user"); user != "" {
+        target = user + "@" + address
+    }
+    host := config.Host{Name: name, Target: target, Tags: tags}
+    if port := firstVar(vars, "ansible_port", "ansible_ssh_port"); port != "" && port != "22" {
+        host.Options = map[string]string{"Port": port}
+    }
+    return host
+}
+
+func firstVar(vars map[string]string, keys ...string) string {
+    for _, key := range keys {
+        if value := vars[key]; value != "" {
+            return value
+        }
+    }
+    return ""
+}
+
+func parseAnsibleINI(r io.Reader) (*ansibleInventory, error) {
+    inv := newAnsibleInventory()
+    section := "ungrouped"
+    kind := "hosts"
+    scanner := bufio.NewScanner(r)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+            continue
+        }
+        if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+            name, suffix, _ := strings.Cut(line[1:len(line)-1], ":")
+            switch suffix {
+            case "":
+                kind = "hosts"
+            case "vars", "children":
+                kind = suffix
+            default:
+                return nil, fmt.Errorf("line %d: unknown section type %q", lineNo, suffix)
+            }
+            section = name
+            inv.group(section)
+            continue
+        }
+
+        switch kind {
+        case "vars":
+            key, value, ok := strings.Cut(line, "=")
+            if !ok {
+                return nil, fmt.Errorf("line %d: expected key=value", lineNo)
+            }
+            fields, err := shellFields(strings.TrimSpace(value))
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            inv.group(section).vars[strings.TrimSpace(key)] = strings.Join(fields, " ")
+        case "children":
+            inv.addChild(section, line)
+        default:
+            fields, err := shellFields(line)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            vars := make(map[string]string)
+            for _, field := range fields[1:] {
+                key, value, ok := strings.Cut(field, "=")
+                if !ok {
+                    return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, field)
+                }
+                vars[key] = value
+            }
+            pattern := fields[0]
+            if host, port, ok := strings.Cut(pattern, ":"); ok && !strings.Contains(port, ":") {
+                if _, err := strconv.Atoi(port); err == nil {
+                    pattern = host
+                    if _, set := vars["ansible_port"]; !set {
+                        vars["ansible_port"] = port
+                    }
+                }
+            }
+            names, err := expandHostPattern(pattern)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            for _, name := range names {
+                inv.addHost(section, name, vars)
+            }
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return inv, nil
+}
+
+func ansibleFromYAML(root *yamlNode) (*ansibleInventory, error) {
+    inv := newAnsibleInventory()
+    for _, top := range root.children {
+        if err := inv.loadYAMLGroup(top.key, top); err != nil {
+            return nil, err
+        }
+    }
+    return inv, nil
+}
+
+func (inv *ansibleInventory) loadYAMLGroup(name string, node *yamlNode) error {
+    if node.value != "" {
+        return fmt.Errorf("group %q must be a mapping", name)
+    }
+    g := inv.group(name)
+    for _, child := range node.children {
+        switch child.key {
+        case "hosts":
+            for _, hostNode := range child.children {
+                vars := make(map[string]string)
+                for _, v := range hostNode.children {
+                    if len(v.children) == 0 {
+                        vars[v.key] = v.value
+                    }
+                }
+                names, err := expandHostPattern(hostNode.key)
+                if err != nil {
+                    return err
+                }
+                for _, host := range names {
+                    inv.addHost(name, host, vars)
+                }
+            }
+        case "vars":
+            for _, v := range child.children {
+                if len(v.children) == 0 {
+                    g.vars[v.key] = v.value
+                }
+            }
+        case "children":
+            for _, sub := range child.children {
+                inv.addChild(name, sub.key)
+                if err := inv.loadYAMLGroup(sub.key, sub); err != nil {
+                    return err
+                }
+            }
+        default:
+            return fmt.Errorf("group %q: unexpected key %q", name, child.key)
+        }
+    }
+    return nil
+}
+
+func expandHostPattern(pattern string) ([]string, error) {
+    open := strings.Index(pattern, "[")
+    if open < 0 {
+        return []string{pattern}, nil
+    }
+    closeIdx := strings.Index(pattern[open:], "]")
+    if closeIdx < 0 {
+        return nil, fmt.Errorf("unterminated range in %q", pattern)
+    }
+    closeIdx += open
+    parts := strings.Split(pattern[open+1:closeIdx], ":")
+    if len(parts) < 2 || len(parts) > 3 {
+        return nil, fmt.Errorf("invalid range in %q", pattern)
+    }
+    step := 1
+    if len(parts) == 3 {
+        parsed, err := strconv.Atoi(parts[2])
+        if err != nil || parsed <= 0 {
+            return nil, fmt.Errorf("invalid range step in %q", pattern)
+        }
+        step = parsed
+    }
+
+    var values []string
+    start, startErr := strconv.Atoi(parts[0])
+    end, endErr := strconv.Atoi(parts[1])
+    switch {
+    case startErr == nil && endErr == nil:
+        width := 0
+        if len(parts[0]) > 1 && parts[0][0] == '0' {
+            width = len(parts[0])
+        }
+        for i := start; i <= end; i += step {
+            values = append(values, fmt.Sprintf("%0*d", width, i))
+        }
+    case len(parts[0]) == 1 && len(parts[1]) == 1:
+        for c := parts[0][0]; c <= parts[1][0]; c += byte(step) {
+            values = append(values, string(c))
+            if int(c)+step > 255 {
+                break
+            }
+        }
+    default:
+        return nil, fmt.Errorf("invalid range in %q", pattern)
+    }
+    if len(values) == 0 {
+        return nil, fmt.Errorf("empty range in %q", pattern)
+    }
+
+    rest, err := expandHostPattern(pattern[closeIdx+1:])
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(values)*len(rest))
+    for _, value := range values {
+        for _, suffix := range rest {
+            names = append(names, pattern[:open]+value+suffix)
+        }
+    }
+    return names, nil
+}
+
+func shellFields(line string) ([]string, error) {
+    var fields []string
+    var current strings.Builder
+    inField := false
+    var quote rune
+    for _, r := range line {
+        switch {
+        case quote != 0:
+            if r == quote {
+                quote = 0
+            } else {
+                current.WriteRune(r)
+            }
+        case r == '"' || r == '\'':
+            quote = r
+            inField = true
+        case r == '#' && !inField:
+            return fields, nil
+        case r == ' ' || r == '\t':
+            if inField {
+                fields = append(fields, current.String())
+                current.Reset()
+                inField = false
+            }
+        default:
+            current.WriteRune(r)
+            inField = true
+        }
+    }
+    if quote != 0 {
+        return nil, errors.New("unterminated quote")
+    }
+    if inField {
+        fields = append(fields, current.String())
+    }
+    return fields, nil
+}
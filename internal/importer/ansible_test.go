@@ -0,0 +1,192 @@
+package importer
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "schh/internal/config"
+)
+
+const iniInventory = `bastion.example
+
+[web]
+web1 ansible_host=10.0.0.1 ansible_user=deploy
+web2:2222
+
+[db]
+db[1:2].example.com ansible_port=22
+
+[prod:children]
+web
+db
+
+[prod:vars]
+ansible_user=ops
+
+[all:vars]
+ansible_port=2200
+`
+
+const yamlInventory = `---
+all:
+  vars:
+    ansible_user: ops   # default login
+  hosts:
+    jump:
+      ansible_host: 192.0.2.1
+  children:
+    web:
+      hosts:
+        web[01:02]:
+          ansible_port: 2222
+    db:
+      vars: {ansible_user: "dba"}
+      hosts:
+        db1: ~
+`
+
+func TestAnsible(t *testing.T) {
+    tests := []struct {
+        name   string
+        file   string
+        data   string
+        prefix string
+        want   []config.Host
+    }{
+        {
+            name: "ini",
+            file: "inventory.ini",
+            data: iniInventory,
+            want: []config.Host{
+                {Name: "bastion.example", Target: "bastion.example", Options: map[string]string{"Port": "2200"}},
+                {Name: "web1", Target: "deploy@10.0.0.1", Tags: []string{"prod", "web"}, Options: map[string]string{"Port": "2200"}},
+                {Name: "web2", Target: "ops@web2", Tags: []string{"prod", "web"}, Options: map[string]string{"Port": "2222"}},
+                {Name: "db1.example.com", Target: "ops@db1.example.com", Tags: []string{"prod", "db"}},
+                {Name: "db2.example.com", Target: "ops@db2.example.com", Tags: []string{"prod", "db"}},
+            },
+        },
+        {
+            name:   "ini with prefix",
+            file:   "hosts.cfg",
+            data:   "[web]\nweb1 ansible_ssh_host=10.0.0.1 ansible_ssh_user=root ansible_ssh_port=2022\n",
+            prefix: "stage-",
+            want: []config.Host{
+                {Name: "stage-web1", Target: "root@10.0.0.1", Tags: []string{"web"}, Options: map[string]string{"Port": "2022"}},
+            },
+        },
+        {
+            name: "ini quoted vars",
+            file: "inventory",
+            data: "[web]\nweb1 ansible_user=\"deploy\"\n\n[web:vars]\nansible_host='10.0.0.9'\n",
+            want: []config.Host{
+                {Name: "web1", Target: "deploy@10.0.0.9", Tags: []string{"web"}},
+            },
+        },
+        {
+            name: "yaml",
+            file: "inventory.yml",
+            data: yamlInventory,
+            want: []config.Host{
+                {Name: "jump", Target: "ops@192.0.2.1"},
+                {Name: "web01", Target: "ops@web01", Tags: []string{"web"}, Options: map[string]string{"Port": "2222"}},
+                {Name: "web02", Target: "ops@web02", Tags: []string{"web"}, Options: map[string]string{"Port": "2222"}},
+                {Name: "db1", Target: "dba@db1", Tags: []string{"db"}},
+            },
+        },
+        {
+            name:   "yaml without extension and with prefix",
+            file:   "inventory",
+            data:   "all:\n  children:\n    prod:\n      children:\n        api:\n          hosts:\n            api-[a:b]:\n",
+            prefix: "p-",
+            want: []config.Host{
+                {Name: "p-api-a", Target: "api-a", Tags: []string{"prod", "api"}},
+                {Name: "p-api-b", Target: "api-b", Tags: []string{"prod", "api"}},
+            },
+        },
+        {
+            name: "yaml quoted keys and flow map",
+            file: "inventory.yaml",
+            data: "web:\n  hosts:\n    \"web 1\": {ansible_host: 10.0.0.1, ansible_port: '2200'}\n",
+            want: []config.Host{
+                {Name: "web 1", Target: "10.0.0.1", Tags: []string{"web"}, Options: map[string]string{"Port": "2200"}},
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := writeInventory(t, tt.file, tt.data)
+            got, err := Ansible(path, tt.prefix)
+            if err != nil {
+                t.Fatalf("Ansible: %v", err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Ansible =\n  %+v\nwant\n  %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestAnsibleErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        file string
+        data string
+        want string
+    }{
+        {"ini unknown section", "inv.ini", "[web:hostvars]\na=1\n", "unknown section type"},
+        {"ini vars without value", "inv.ini", "[web:vars]\nansible_user\n", "expected key=value"},
+        {"ini host attribute without value", "inv.ini", "[web]\nweb1 ansible_user\n", "expected key=value"},
+        {"ini unterminated quote", "inv.ini", "[web]\nweb1 ansible_user=\"deploy\n", "unterminated quote"},
+        {"ini bad range", "inv.ini", "[web]\nweb[1:]\n", "invalid range"},
+        {"ini unterminated range", "inv.ini", "[web]\nweb[1:3\n", "unterminated range"},
+        {"ini group cycle", "inv.ini", "[a]\nhost1\n\n[a:children]\nb\n\n[b:children]\na\n", "its own ancestor"},
+        {"yaml list", "inv.yml", "all:\n  hosts:\n    - web1\n", "lists are not supported"},
+        {"yaml block scalar", "inv.yml", "all:\n  vars:\n    motd: |\n      hi\n", "unsupported YAML value"},
+        {"yaml flow sequence", "inv.yml", "all:\n  vars:\n    ports: [22, 2222]\n", "unsupported YAML value"},
+        {"yaml tab indentation", "inv.yml", "all:\n  hosts:\n \tweb1:\n", "tabs are not allowed"},
+        {"yaml missing colon", "inv.yml", "all:\n  hosts\n", "expected 'key: value'"},
+        {"yaml unknown group key", "inv.yml", "all:\n  hostvars:\n    a: b\n", `unexpected key "hostvars"`},
+        {"yaml group scalar", "inv.yml", "all: web1\n", "must be a mapping"},
+        {"yaml unterminated flow map", "inv.yml", "all:\n  vars: {a: b\n", "unterminated flow mapping"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := writeInventory(t, tt.file, tt.data)
+            _, err := Ansible(path, "")
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Fatalf("Ansible error = %v, want %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestExpandHostPattern(t *testing.T) {
+    tests := []struct {
+        pattern string
+        want    []string
+    }{
+        {"web", []string{"web"}},
+        {"web[1:3]", []string{"web1", "web2", "web3"}},
+        {"web[01:10:4]", []string{"web01", "web05", "web09"}},
+        {"db-[a:c].example", []string{"db-a.example", "db-b.example", "db-c.example"}},
+        {"r[1:2]n[1:2]", []string{"r1n1", "r1n2", "r2n1", "r2n2"}},
+    }
+    for _, tt := range tests {
+        got, err := expandHostPattern(tt.pattern)
+        if err != nil || !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("expandHostPattern(%q) = %q, %v; want %q", tt.pattern, got, err, tt.want)
+        }
+    }
+}
+
+func writeInventory(t *testing.T, name, data string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
@@ -0,0 +1,213 @@
+package importer
+
+import (
+    "bufio"
+    "errors"
+    "os"
+    "strings"
+
+    "schh/internal/config"
+    "schh/internal/exporter"
+)
+
+func SSHConfig(path, prefix string) ([]config.Host, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    type block struct {
+        aliases  []string
+        hostName string
+        user     string
+        tags     []string
+        options  map[string]string
+    }
+    var blocks []*block
+    var current *block
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if current != nil && strings.HasPrefix(line, exporter.TagsComment) {
+            for _, tag := range strings.Split(strings.TrimPrefix(line, exporter.TagsComment), ",") {
+                if tag = strings.TrimSpace(tag); tag != "" {
+                    current.tags = append(current.tags, tag)
+                }
+            }
+            continue
+        }
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        keyword, value := splitSSHKeyword(line)
+        switch strings.ToLower(keyword) {
+        case "host":
+            current = &block{options: make(map[string]string)}
+            fields, err := sshFields(value)
+            if err != nil {
+                return nil, err
+            }
+            for _, alias := range fields {
+                if !strings.ContainsAny(alias, "*?!") {
+                    current.aliases = append(current.aliases, alias)
+                }
+            }
+            blocks = append(blocks, current)
+        case "match":
+            current = nil
+        case "hostname":
+            if current != nil && current.hostName == "" {
+                current.hostName = unquoteSSHValue(value)
+            }
+        case "user":
+            if current != nil && current.user == "" {
+                current.user = unquoteSSHValue(value)
+            }
+        default:
+            if current == nil {
+                continue
+            }
+            if _, exists := current.options[keyword]; !exists {
+                current.options[keyword] = value
+            }
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    aliases := make(map[string]bool)
+    for _, b := range blocks {
+        for _, alias := range b.aliases {
+            aliases[alias] = true
+        }
+    }
+
+    var hosts []config.Host
+    for _, b := range blocks {
+        for _, alias := range b.aliases {
+            target := b.hostName
+            if target == "" {
+                target = alias
+            }
+            if b.user != "" {
+                target = b.user + "@" + target
+            }
+            host := config.Host{Name: prefix + alias, Target: target, Tags: b.tags}
+            for key, value := range b.options {
+                if strings.EqualFold(key, "ProxyJump") {
+                    if via, ok := viaFromProxyJump(value, prefix, aliases); ok {
+                        host.Via = via
+                        continue
+                    }
+                }
+                if strings.EqualFold(key, "SendEnv") {
+                    if patterns, err := sshFields(value); err == nil {
+                        host.SendEnv = patterns
+                        continue
+                    }
+                }
+                if strings.EqualFold(key, "SetEnv") {
+                    if env, ok := envFromSetEnv(value); ok {
+                        host.Env = env
+                        continue
+                    }
+                }
+                if host.Options == nil {
+                    host.Options = make(map[string]string, len(b.options))
+                }
+                host.Options[key] = unquoteSSHValue(value)
+            }
+            hosts = append(hosts, host)
+        }
+    }
+    return hosts, nil
+}
+
+func envFromSetEnv(value string) (map[string]string, bool) {
+    fields, err := sshFields(value)
+    if err != nil || len(fields) == 0 {
+        return nil, false
+    }
+    env := make(map[string]string, len(fields))
+    for _, field := range fields {
+        name, val, ok := strings.Cut(field, "=")
+        if !ok || name == "" {
+            return nil, false
+        }
+        env[name] = val
+    }
+    return env, true
+}
+
+func splitSSHKeyword(line string) (string, string) {
+    idx := strings.IndexAny(line, " \t=")
+    if idx < 0 {
+        return line, ""
+    }
+    keyword := line[:idx]
+    value := strings.TrimLeft(line[idx:], " \t")
+    value = strings.TrimPrefix(value, "=")
+    return keyword, strings.TrimSpace(value)
+}
+
+func unquoteSSHValue(value string) string {
+    if fields, err := sshFields(value); err == nil && len(fields) == 1 {
+        return fields[0]
+    }
+    return value
+}
+
+func sshFields(line string) ([]string, error) {
+    var fields []string
+    var current strings.Builder
+    inField := false
+    var quote byte
+    for i := 0; i < len(line); i++ {
+        c := line[i]
+        switch {
+        case c == '\\' && i+1 < len(line) && strings.IndexByte(`\"' `, line[i+1]) >= 0:
+            i++
+            current.WriteByte(line[i])
+            inField = true
+        case quote != 0:
+            if c == quote {
+                quote = 0
+            } else {
+                current.WriteByte(c)
+            }
+        case c == '"' || c == '\'':
+            quote = c
+            inField = true
+        case c == ' ' || c == '\t':
+            if inField {
+                fields = append(fields, current.String())
+                current.Reset()
+                inField = false
+            }
+        default:
+            current.WriteByte(c)
+            inField = true
+        }
+    }
+    if quote != 0 {
+        return nil, errors.New("unterminated quote")
+    }
+    if inField {
+        fields = append(fields, current.String())
+    }
+    return fields, nil
+}
+
+func viaFromProxyJump(value, prefix string, aliases map[string]bool) ([]string, bool) {
+    var via []string
+    for _, hop := range strings.Split(value, ",") {
+        hop = strings.TrimSpace(hop)
+        if !aliases[hop] {
+            return nil, false
+        }
+        via = append(via, prefix+hop)
+    }
+    return via, len(via) > 0
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "schh/internal/config"
+    "schh/internal/exporter"
+)
+
+func TestSSHConfigRoundTrip(t *testing.T) {
+    hosts := []config.Host{
+        {
+            Name:   "bastion",
+            Target: "jump@bastion.example",
+            Options: map[string]string{
+                "Port":         "2222",
+                "IdentityFile": "~/keys/my key",
+                "ProxyCommand": "ssh -W %h:%p gateway",
+            },
+        },
+        {
+            Name:    "prod",
+            Target:  "deploy@prod.example",
+            Via:     []string{"bastion"},
+            Tags:    []string{"prod", "eu"},
+            SendEnv: []string{"LANG", "LC_*"},
+            Env: map[string]string{
+                "APP":      "api",
+                "GREETING": "hello world",
+                "QUOTED":   `say "hi"`,
+                "PATHS":    `C:\tools\bin;D:\x y`,
+                "SINGLE":   "it's",
+            },
+        },
+    }
+
+    var buf bytes.Buffer
+    if err := exporter.Write(&buf, "ssh-config", hosts); err != nil {
+        t.Fatalf("export: %v", err)
+    }
+    path := filepath.Join(t.TempDir(), "config")
+    if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    imported, err := SSHConfig(path, "")
+    if err != nil {
+        t.Fatalf("import: %v", err)
+    }
+    if !reflect.DeepEqual(imported, hosts) {
+        t.Errorf("round trip changed the hosts\nexported:\n%s\ngot:  %+v\nwant: %+v", buf.String(), imported, hosts)
+    }
+}
+
+func TestSSHConfigSetEnv(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config")
+    data := `Host a
+    SetEnv "FOO=a b" BAR=c BAZ="x y" ESC="q\"uote"
+Host b
+    SetEnv "ONLY=one two"
+Host c
+    SetEnv = PLAIN=1
+`
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    hosts, err := SSHConfig(path, "")
+    if err != nil {
+        t.Fatalf("import: %v", err)
+    }
+    want := []map[string]string{
+        {"FOO": "a b", "BAR": "c", "BAZ": "x y", "ESC": `q"uote`},
+        {"ONLY": "one two"},
+        {"PLAIN": "1"},
+    }
+    if len(hosts) != len(want) {
+        t.Fatalf("imported %d hosts, want %d", len(hosts), len(want))
+    }
+    for i, h := range hosts {
+        if !reflect.DeepEqual(h.Env, want[i]) {
+            t.Errorf("host %s: Env = %q, want %q", h.Name, h.Env, want[i])
+        }
+        if len(h.Options) > 0 {
+            t.Errorf("host %s: SetEnv kept as an option: %q", h.Name, h.Options)
+        }
+    }
+}
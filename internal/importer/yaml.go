@@ -0,0 +1,144 @@
+package importer
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strings"
+)
+
+type yamlNode struct {
+    key      string
+    value    string
+    children []*yamlNode
+}
+
+func parseYAML(r io.Reader) (*yamlNode, error) {
+    type frame struct {
+        indent int
+        node   *yamlNode
+    }
+    root := &yamlNode{}
+    stack := []frame{{indent: -1, node: root}}
+    scanner := bufio.NewScanner(r)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        content := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t\r")
+        text := strings.TrimSpace(content)
+        if text == "" || text == "---" || text == "..." {
+            continue
+        }
+        indent := len(content) - len(strings.TrimLeft(content, " "))
+        if strings.HasPrefix(content[indent:], "\t") {
+            return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+        }
+        if text == "-" || strings.HasPrefix(text, "- ") {
+            return nil, fmt.Errorf("line %d: YAML lists are not supported in inventories", lineNo)
+        }
+        key, value, err := cutYAMLKey(text)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %w", lineNo, err)
+        }
+
+        for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+            stack = stack[:len(stack)-1]
+        }
+        parent := stack[len(stack)-1].node
+        node := &yamlNode{key: key}
+        parent.children = append(parent.children, node)
+
+        switch {
+        case value == "":
+            stack = append(stack, frame{indent: indent, node: node})
+        case strings.HasPrefix(value, "{"):
+            children, err := parseYAMLFlowMap(value)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            node.children = children
+        case value == "|" || value == ">" || strings.HasPrefix(value, "["):
+            return nil, fmt.Errorf("line %d: unsupported YAML value for %q", lineNo, key)
+        default:
+            node.value = yamlScalar(value)
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return root, nil
+}
+
+func stripYAMLComment(line string) string {
+    var quote rune
+    for i, r := range line {
+        switch {
+        case quote != 0:
+            if r == quote {
+                quote = 0
+            }
+        case r == '"' || r == '\'':
+            quote = r
+        case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+            return line[:i]
+        }
+    }
+    return line
+}
+
+func cutYAMLKey(text string) (string, string, error) {
+    if text[0] == '"' || text[0] == '\'' {
+        end := strings.IndexByte(text[1:], text[0])
+        if end < 0 {
+            return "", "", fmt.Errorf("unterminated quoted key")
+        }
+        key := text[1 : end+1]
+        rest := text[end+2:]
+        if !strings.HasPrefix(rest, ":") {
+            return "", "", fmt.Errorf("expected ':' after key %q", key)
+        }
+        return key, strings.TrimSpace(rest[1:]), nil
+    }
+    if strings.HasSuffix(text, ":") {
+        return text[:len(text)-1], "", nil
+    }
+    idx := strings.Index(text, ": ")
+    if idx < 0 {
+        return "", "", fmt.Errorf("expected 'key: value', got %q", text)
+    }
+    return text[:idx], strings.TrimSpace(text[idx+2:]), nil
+}
+
+func parseYAMLFlowMap(text string) ([]*yamlNode, error) {
+    if !strings.HasSuffix(text, "}") {
+        return nil, fmt.Errorf("unterminated flow mapping %q", text)
+    }
+    body := strings.TrimSpace(text[1 : len(text)-1])
+    if body == "" {
+        return nil, nil
+    }
+    var nodes []*yamlNode
+    for _, entry := range strings.Split(body, ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        key, value, err := cutYAMLKey(entry)
+        if err != nil {
+            return nil, err
+        }
+        nodes = append(nodes, &yamlNode{key: key, value: yamlScalar(value)})
+    }
+    return nodes, nil
+}
+
+func yamlScalar(value string) string {
+    if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+        return value[1 : len(value)-1]
+    }
+    switch value {
+    case "~", "null", "Null", "NULL":
+        return ""
+    }
+    return value
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+)
+
+var ErrUnsupported = errors.New("not supported by this backend")
+
+var Backends = []string{"screen", "tmux"}
+
+type backend interface {
+    name() string
+    list() ([]Info, error)
+    socketDir() (string, error)
+    start(sessionID string, command []string, screenrc string) error
+    attachCommand(sessionID string, mode AttachMode) (string, []string)
+    joinCommand(owner, sessionID string) (string, []string, error)
+    rename(sessionID, newName string) error
+    share(sessionID, user string, readOnly bool) error
+    unshare(sessionID, user string) error
+    hardcopy(sessionID string) (string, error)
+    kill(sessionID string) error
+    currentSession() (string, bool)
+}
+
+var (
+    current backend = screenBackend{}
+    trace   io.Writer
+)
+
+func SetBackend(name string) error {
+    switch name {
+    case "", "screen":
+        current = screenBackend{}
+    case "tmux":
+        current = tmuxBackend{}
+    default:
+        return fmt.Errorf("unknown backend %q (expected %s)", name, strings.Join(Backends, " or "))
+    }
+    return nil
+}
+
+func BackendName() string {
+    return current.name()
+}
+
+func SetTrace(w io.Writer) {
+    trace = w
+}
@@ -0,0 +1,46 @@
+package session
+
+import "time"
+
+const (
+    EventStarted  = "started"
+    EventAttached = "attached"
+    EventDetached = "detached"
+    EventEnded    = "ended"
+)
+
+type Event struct {
+    Type    string    `json:"type"`
+    Host    string    `json:"host,omitempty"`
+    Session Info      `json:"session"`
+    Time    time.Time `json:"time"`
+}
+
+func DiffSessions(prev, next []Info, now time.Time) []Event {
+    before := make(map[string]Info, len(prev))
+    for _, s := range prev {
+        before[s.ID] = s
+    }
+    var events []Event
+    for _, s := range next {
+        old, ok := before[s.ID]
+        delete(before, s.ID)
+        switch {
+        case !ok:
+            events = append(events, Event{Type: EventStarted, Session: s, Time: now})
+            if s.Attached {
+                events = append(events, Event{Type: EventAttached, Session: s, Time: now})
+            }
+        case s.Attached && !old.Attached:
+            events = append(events, Event{Type: EventAttached, Session: s, Time: now})
+        case !s.Attached && old.Attached:
+            events = append(events, Event{Type: EventDetached, Session: s, Time: now})
+        }
+    }
+    for _, s := range prev {
+        if _, gone := before[s.ID]; gone {
+            events = append(events, Event{Type: EventEnded, Session: s, Time: now})
+        }
+    }
+    return events
+}
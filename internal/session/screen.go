@@ -0,0 +1,175 @@
+package session
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+type screenBackend struct{}
+
+func (screenBackend) name() string {
+    return "screen"
+}
+
+func (screenBackend) list() ([]Info, error) {
+    output, err := command("screen", "-ls").CombinedOutput()
+    if err != nil {
+        var exitErr *exec.ExitError
+        if !(errors.As(err, &exitErr) && len(output) > 0) {
+            return nil, err
+        }
+    }
+    return parseScreenOutput(output)
+}
+
+func (screenBackend) socketDir() (string, error) {
+    if dir := os.Getenv("SCREENDIR"); dir != "" {
+        return dir, nil
+    }
+    output, _ := command("screen", "-ls").CombinedOutput()
+    for _, line := range strings.Split(string(output), "\n") {
+        line = strings.TrimSpace(line)
+        if idx := strings.Index(line, " in /"); idx >= 0 && strings.Contains(strings.ToLower(line), "socket") {
+            return strings.TrimSuffix(line[idx+len(" in "):], "."), nil
+        }
+    }
+    return "", errors.New("unable to find the screen socket directory")
+}
+
+func parseScreenOutput(output []byte) ([]Info, error) {
+    scanner := bufio.NewScanner(bytes.NewReader(output))
+    var sessions []Info
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" {
+            continue
+        }
+        fields := strings.Fields(line)
+        var candidate string
+        for _, field := range fields {
+            if strings.Contains(field, ".") {
+                candidate = field
+                break
+            }
+        }
+        if candidate == "" {
+            continue
+        }
+        dotIdx := strings.Index(candidate, ".")
+        if dotIdx < 0 || dotIdx+1 >= len(candidate) {
+            continue
+        }
+        name := candidate[dotIdx+1:]
+        if !strings.HasPrefix(name, sessionPrefix) {
+            continue
+        }
+        lower := strings.ToLower(line)
+        attached := strings.Contains(lower, "attached)") && !strings.Contains(lower, "detached)")
+        sessions = append(sessions, newInfo(candidate, name, attached))
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return sessions, nil
+}
+
+func (screenBackend) start(sessionID string, cmd []string, screenrc string) error {
+    args := []string{"-dmS", sessionID}
+    if screenrc != "" {
+        args = append(args, "-c", screenrc)
+    }
+    args = append(args, cmd...)
+    return command("screen", args...).Run()
+}
+
+func (screenBackend) attachCommand(sessionID string, mode AttachMode) (string, []string) {
+    switch mode {
+    case AttachShared:
+        return "screen", []string{"-x", sessionID}
+    case AttachSteal:
+        return "screen", []string{"-d", "-r", sessionID}
+    case AttachPowerDetach:
+        return "screen", []string{"-D", "-r", sessionID}
+    default:
+        return "screen", []string{"-r", sessionID}
+    }
+}
+
+func (screenBackend) joinCommand(owner, sessionID string) (string, []string, error) {
+    return "screen", []string{"-x", owner + "/" + sessionID}, nil
+}
+
+func (screenBackend) rename(sessionID, newName string) error {
+    return screenCommand(sessionID, "sessionname", newName)
+}
+
+func (screenBackend) share(sessionID, user string, readOnly bool) error {
+    if err := screenCommand(sessionID, "multiuser", "on"); err != nil {
+        return err
+    }
+    if err := screenCommand(sessionID, "acladd", user); err != nil {
+        return err
+    }
+    perm := "+w"
+    if readOnly {
+        perm = "-w"
+    }
+    return screenCommand(sessionID, "aclchg", user, perm, "#")
+}
+
+func (screenBackend) unshare(sessionID, user string) error {
+    return screenCommand(sessionID, "acldel", user)
+}
+
+func (screenBackend) hardcopy(sessionID string) (string, error) {
+    dir, err := os.MkdirTemp("", "schh-hardcopy-")
+    if err != nil {
+        return "", err
+    }
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "hardcopy")
+    if err := screenCommand(sessionID, "hardcopy", "-h", path); err != nil {
+        return "", err
+    }
+
+    var data []byte
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        data, err = os.ReadFile(path)
+        if err == nil || !errors.Is(err, os.ErrNotExist) || time.Now().After(deadline) {
+            break
+        }
+        time.Sleep(50 * time.Millisecond)
+    }
+    return string(data), err
+}
+
+func (screenBackend) kill(sessionID string) error {
+    return screenCommand(sessionID, "quit")
+}
+
+func (screenBackend) currentSession() (string, bool) {
+    sty := os.Getenv("STY")
+    return sty, sty != ""
+}
+
+func screenCommand(sessionID string, args ...string) error {
+    if sessionID == "" {
+        return errors.New("missing session identifier")
+    }
+    output, err := command("screen", append([]string{"-S", sessionID, "-X"}, args...)...).CombinedOutput()
+    if err != nil {
+        if msg := strings.TrimSpace(string(output)); msg != "" {
+            return fmt.Errorf("%w: %s", err, msg)
+        }
+        return err
+    }
+    return nil
+}
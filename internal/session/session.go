@@ -1,32 +1,58 @@
 package session
 
 import (
-    "bufio"
-    "bytes"
     "errors"
     "fmt"
     "math/rand"
     "os"
     "os/exec"
+    "path/filepath"
     "strings"
     "sync"
     "syscall"
     "time"
 )
 
+const sessionPrefix = "schh_"
+
 type Info struct {
-    ID    string
-    Label string
+    ID        string `json:"id"`
+    Name      string `json:"name"`
+    HostToken string `json:"host_token,omitempty"`
+    Label     string `json:"label,omitempty"`
+    Attached  bool   `json:"attached"`
+}
+
+type ScreenOptions struct {
+    UTF8       bool
+    Scrollback int
+    Title      string
 }
 
+type AttachMode int
+
+const (
+    AttachDefault AttachMode = iota
+    AttachShared
+    AttachSteal
+    AttachPowerDetach
+)
+
 var (
     adjectives = []string{"bold", "bright", "calm", "clever", "daring", "eager", "gentle", "lively", "nimble", "radiant", "steady", "swift", "vivid"}
     nouns      = []string{"albatross", "badger", "copper", "dolphin", "falcon", "juniper", "lynx", "maple", "otter", "pine", "raven", "spruce", "swift", "walnut"}
     rng        = rand.New(rand.NewSource(time.Now().UnixNano()))
     rngMu      sync.Mutex
+
+    lister       func() ([]Info, error)
+    legacyNotice func([]Info)
 )
 
 func SanitizeToken(input string) string {
+    return sanitize(input, '-')
+}
+
+func sanitize(input string, underscore rune) string {
     var builder strings.Builder
     for _, r := range input {
         switch {
@@ -39,7 +65,7 @@ func SanitizeToken(input string) string {
         case r == '-':
             builder.WriteRune('-')
         case r == '_':
-            builder.WriteRune('_')
+            builder.WriteRune(underscore)
         case r == '.':
             builder.WriteRune('-')
         }
@@ -56,91 +82,278 @@ func BuildSessionID(hostName, sessionName string) (string, error) {
     if hostToken == "" || sessionToken == "" {
         return "", errors.New("invalid host or session name")
     }
-    id := fmt.Sprintf("schh_%s_%s", hostToken, sessionToken)
+    id := sessionPrefix + hostToken + "_" + sessionToken
     if len(id) >= 240 {
         return "", errors.New("session identifier too long")
     }
     return id, nil
 }
 
+func ParseSessionID(id string) (string, string, bool) {
+    name := id
+    if dotIdx := strings.Index(id, "."); dotIdx >= 0 {
+        name = id[dotIdx+1:]
+    }
+    rest, ok := strings.CutPrefix(name, sessionPrefix)
+    if !ok {
+        return "", "", false
+    }
+    hostToken, label, ok := strings.Cut(rest, "_")
+    if !ok || hostToken == "" || label == "" || strings.Contains(label, "_") {
+        return "", "", false
+    }
+    return hostToken, label, true
+}
+
+type Migration struct {
+    Session  Info
+    HostName string
+    Label    string
+    NewID    string
+}
+
+func PlanMigration(sessions []Info, hostNames []string) ([]Migration, []Info) {
+    var planned []Migration
+    var unresolved []Info
+    for _, s := range sessions {
+        if s.HostToken != "" {
+            continue
+        }
+        var matches []Migration
+        for _, hostName := range hostNames {
+            prefix := sessionPrefix + sanitize(hostName, '_') + "_"
+            rest, ok := strings.CutPrefix(s.Name, prefix)
+            if !ok || rest == "" {
+                continue
+            }
+            label := SanitizeToken(rest)
+            newID, err := BuildSessionID(hostName, label)
+            if err != nil {
+                continue
+            }
+            matches = append(matches, Migration{Session: s, HostName: hostName, Label: label, NewID: newID})
+        }
+        if len(matches) == 1 {
+            planned = append(planned, matches[0])
+        } else {
+            unresolved = append(unresolved, s)
+        }
+    }
+    return planned, unresolved
+}
+
 func ListSessionsForHost(hostName string) ([]Info, error) {
     sanitized := SanitizeToken(hostName)
     if sanitized == "" {
         return []Info{}, nil
     }
-
-    cmd := exec.Command("screen", "-ls")
-    output, err := cmd.CombinedOutput()
-    if err != nil {
-        var exitErr *exec.ExitError
-        if !(errors.As(err, &exitErr) && len(output) > 0) {
-            return nil, err
-        }
-    }
-    prefix := fmt.Sprintf("schh_%s_", sanitized)
-    sessions, err := parseScreenOutput(output, prefix)
+    all, err := ListAllSessions()
     if err != nil {
         return nil, err
     }
+    sessions := []Info{}
+    for _, s := range all {
+        if s.HostToken == sanitized {
+            sessions = append(sessions, s)
+        }
+    }
     return sessions, nil
 }
 
-func parseScreenOutput(output []byte, prefix string) ([]Info, error) {
-    scanner := bufio.NewScanner(bytes.NewReader(output))
-    var sessions []Info
-    for scanner.Scan() {
-        line := strings.TrimSpace(scanner.Text())
-        if line == "" {
-            continue
-        }
-        fields := strings.Fields(line)
-        var candidate string
-        for _, field := range fields {
-            if strings.Contains(field, ".") {
-                candidate = field
-                break
-            }
-        }
-        if candidate == "" {
-            continue
+func UseLister(fn func() ([]Info, error)) {
+    lister = fn
+}
+
+func OnLegacySessions(fn func([]Info)) {
+    legacyNotice = fn
+}
+
+func ListAllSessions() ([]Info, error) {
+    sessions, err := listAllSessions()
+    if err == nil {
+        noticeLegacy(sessions)
+    }
+    return sessions, err
+}
+
+func listAllSessions() ([]Info, error) {
+    if lister != nil {
+        if sessions, err := lister(); err == nil {
+            return sessions, nil
         }
-        dotIdx := strings.Index(candidate, ".")
-        if dotIdx < 0 || dotIdx+1 >= len(candidate) {
-            continue
+    }
+    return ScanSessions()
+}
+
+func noticeLegacy(sessions []Info) {
+    if legacyNotice == nil {
+        return
+    }
+    var legacy []Info
+    for _, s := range sessions {
+        if s.HostToken == "" && strings.HasPrefix(s.Name, sessionPrefix) {
+            legacy = append(legacy, s)
         }
-        name := candidate[dotIdx+1:]
-        if !strings.HasPrefix(name, prefix) {
-            continue
+    }
+    if len(legacy) > 0 {
+        notice := legacyNotice
+        legacyNotice = nil
+        notice(legacy)
+    }
+}
+
+func ScanSessions() ([]Info, error) {
+    return current.list()
+}
+
+func SocketDir() (string, error) {
+    return current.socketDir()
+}
+
+func StartDetachedSession(sessionID string, command []string, screenrc string) error {
+    if sessionID == "" || len(command) == 0 {
+        return errors.New("missing session identifier or command")
+    }
+    return current.start(sessionID, command, screenrc)
+}
+
+func (o ScreenOptions) IsZero() bool {
+    return !o.UTF8 && o.Scrollback == 0 && o.Title == ""
+}
+
+func WriteScreenrc(path string, opts ScreenOptions) error {
+    var b strings.Builder
+    userRC := os.Getenv("SCREENRC")
+    if userRC == "" {
+        if home, err := os.UserHomeDir(); err == nil {
+            userRC = filepath.Join(home, ".screenrc")
         }
-        label := strings.TrimPrefix(name, prefix)
-        if label == "" {
-            continue
+    }
+    if userRC != "" {
+        if _, err := os.Stat(userRC); err == nil {
+            fmt.Fprintf(&b, "source %s\n", screenQuote(userRC))
         }
-        sessions = append(sessions, Info{ID: candidate, Label: label})
     }
-    if err := scanner.Err(); err != nil {
-        return nil, err
+    if opts.UTF8 {
+        b.WriteString("defutf8 on\n")
     }
-    return sessions, nil
+    if opts.Scrollback > 0 {
+        fmt.Fprintf(&b, "defscrollback %d\n", opts.Scrollback)
+    }
+    if opts.Title != "" {
+        fmt.Fprintf(&b, "shelltitle %s\n", screenQuote(opts.Title))
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+    return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+func screenQuote(value string) string {
+    return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
 }
 
-func StartDetachedSession(sessionID, target string) error {
-    if sessionID == "" || target == "" {
-        return errors.New("missing session identifier or target")
+func RenameSession(sessionID, newName string) error {
+    if newName == "" {
+        return errors.New("missing session name")
+    }
+    return current.rename(sessionID, newName)
+}
+
+func ShareSession(sessionID, user string, readOnly bool) error {
+    if user == "" {
+        return errors.New("missing user name")
+    }
+    return current.share(sessionID, user, readOnly)
+}
+
+func UnshareSession(sessionID, user string) error {
+    if user == "" {
+        return errors.New("missing user name")
+    }
+    return current.unshare(sessionID, user)
+}
+
+func AttachSessionWait(sessionID string, mode AttachMode) error {
+    if sessionID == "" {
+        return errors.New("missing session identifier")
     }
-    cmd := exec.Command("screen", "-dmS", sessionID, "ssh", "-tt", target)
+    name, args := current.attachCommand(sessionID, mode)
+    cmd := command(name, args...)
+    cmd.Stdin = os.Stdin
+    cmd.Stdout = os.Stdout
+    cmd.Stderr = os.Stderr
     return cmd.Run()
 }
 
-func AttachSession(sessionID string) error {
+func Hardcopy(sessionID string, lines int) ([]string, error) {
+    output, err := current.hardcopy(sessionID)
+    if err != nil {
+        return nil, err
+    }
+    all := strings.Split(strings.TrimRight(output, " \t\r\n"), "\n")
+    for i, line := range all {
+        all[i] = strings.TrimRight(line, " \t\r")
+    }
+    if lines > 0 && len(all) > lines {
+        all = all[len(all)-lines:]
+    }
+    return all, nil
+}
+
+func KillSession(sessionID string) error {
     if sessionID == "" {
         return errors.New("missing session identifier")
     }
-    screenPath, err := exec.LookPath("screen")
+    return current.kill(sessionID)
+}
+
+func JoinSession(owner, sessionID string) error {
+    if owner == "" || sessionID == "" {
+        return errors.New("missing owner or session identifier")
+    }
+    name, args, err := current.joinCommand(owner, sessionID)
     if err != nil {
         return err
     }
-    return syscall.Exec(screenPath, []string{"screen", "-r", sessionID}, os.Environ())
+    return execReplace(name, args)
+}
+
+func Current() (string, string, bool) {
+    id, ok := current.currentSession()
+    if !ok {
+        return "", "", false
+    }
+    return ParseSessionID(id)
+}
+
+func newInfo(id, name string, attached bool) Info {
+    info := Info{ID: id, Name: name, Attached: attached}
+    if hostToken, label, ok := ParseSessionID(name); ok {
+        info.HostToken = hostToken
+        info.Label = label
+    }
+    return info
+}
+
+func execReplace(name string, args []string) error {
+    path, err := exec.LookPath(name)
+    if err != nil {
+        return err
+    }
+    traceCommand(name, args)
+    return syscall.Exec(path, append([]string{name}, args...), os.Environ())
+}
+
+func command(name string, args ...string) *exec.Cmd {
+    traceCommand(name, args)
+    return exec.Command(name, args...)
+}
+
+func traceCommand(name string, args []string) {
+    if trace != nil {
+        fmt.Fprintf(trace, "+ %s %s\n", name, strings.Join(args, " "))
+    }
 }
 
 func GenerateSessionLabel() string {
@@ -0,0 +1,122 @@
+package session
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+)
+
+type tmuxBackend struct{}
+
+func (tmuxBackend) name() string {
+    return "tmux"
+}
+
+func (tmuxBackend) list() ([]Info, error) {
+    output, err := command("tmux", "list-sessions", "-F", "#{session_name}\t#{session_attached}").CombinedOutput()
+    if err != nil {
+        msg := string(output)
+        if strings.Contains(msg, "no server running") || strings.Contains(msg, "No such file or directory") {
+            return []Info{}, nil
+        }
+        return nil, tmuxError(err, output)
+    }
+    sessions := []Info{}
+    for _, line := range strings.Split(string(output), "\n") {
+        name, attached, ok := strings.Cut(strings.TrimSpace(line), "\t")
+        if !ok || !strings.HasPrefix(name, sessionPrefix) {
+            continue
+        }
+        sessions = append(sessions, newInfo(name, name, attached != "" && attached != "0"))
+    }
+    return sessions, nil
+}
+
+func (tmuxBackend) socketDir() (string, error) {
+    return "", fmt.Errorf("tmux keeps all sessions behind one socket: %w", ErrUnsupported)
+}
+
+func (tmuxBackend) start(sessionID string, cmd []string, screenrc string) error {
+    args := append([]string{"new-session", "-d", "-s", sessionID, "--"}, cmd...)
+    output, err := command("tmux", args...).CombinedOutput()
+    if err != nil {
+        return tmuxError(err, output)
+    }
+    return nil
+}
+
+func (tmuxBackend) attachCommand(sessionID string, mode AttachMode) (string, []string) {
+    args := []string{"attach-session", "-t", tmuxTarget(sessionID)}
+    if mode == AttachSteal || mode == AttachPowerDetach {
+        args = append(args, "-d")
+    }
+    return "tmux", args
+}
+
+func (tmuxBackend) joinCommand(owner, sessionID string) (string, []string, error) {
+    return "", nil, fmt.Errorf("joining another user's session: %w", ErrUnsupported)
+}
+
+func (tmuxBackend) rename(sessionID, newName string) error {
+    return tmuxRun("rename-session", "-t", tmuxTarget(sessionID), newName)
+}
+
+func (tmuxBackend) share(sessionID, user string, readOnly bool) error {
+    return fmt.Errorf("sharing sessions: %w", ErrUnsupported)
+}
+
+func (tmuxBackend) unshare(sessionID, user string) error {
+    return fmt.Errorf("sharing sessions: %w", ErrUnsupported)
+}
+
+func (tmuxBackend) hardcopy(sessionID string) (string, error) {
+    output, err := command("tmux", "capture-pane", "-p", "-J", "-S", "-", "-t", tmuxTarget(sessionID)+":").Output()
+    if err != nil {
+        return "", tmuxError(err, nil)
+    }
+    return string(output), nil
+}
+
+func (tmuxBackend) kill(sessionID string) error {
+    return tmuxRun("kill-session", "-t", tmuxTarget(sessionID))
+}
+
+func (tmuxBackend) currentSession() (string, bool) {
+    if os.Getenv("TMUX") == "" {
+        return "", false
+    }
+    output, err := command("tmux", "display-message", "-p", "#S").Output()
+    if err != nil {
+        return "", false
+    }
+    name := strings.TrimSpace(string(output))
+    return name, name != ""
+}
+
+func tmuxTarget(sessionID string) string {
+    if _, name, ok := strings.Cut(sessionID, "."); ok {
+        sessionID = name
+    }
+    return "=" + sessionID
+}
+
+func tmuxRun(args ...string) error {
+    output, err := command("tmux", args...).CombinedOutput()
+    if err != nil {
+        return tmuxError(err, output)
+    }
+    return nil
+}
+
+func tmuxError(err error, output []byte) error {
+    var exitErr *exec.ExitError
+    if len(output) == 0 && errors.As(err, &exitErr) {
+        output = exitErr.Stderr
+    }
+    if msg := strings.TrimSpace(string(output)); msg != "" {
+        return fmt.Errorf("%w: %s", err, msg)
+    }
+    return err
+}
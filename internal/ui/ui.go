@@ -23,8 +23,11 @@ type Choice struct {
     Action    Action
     SessionID string
     Label     string
+    Mode      session.AttachMode
 }
 
+const previewLines = 15
+
 var ErrCanceled = errors.New("canceled by user")
 
 func ChooseSession(hostName string, sessions []session.Info, in io.Reader, out io.Writer) (Choice, error) {
@@ -47,11 +50,21 @@ func ChooseSession(hostName string, sessions []session.Info, in io.Reader, out i
 
     for {
         fmt.Fprintf(out, "\nActive sessions for %s:\n", hostName)
+        anyAttached := false
         for idx, info := range sessions {
-            fmt.Fprintf(out, "  %d) %s\n", idx+1, info.Label)
+            marker := ""
+            if info.Attached {
+                marker = "  (attached)"
+                anyAttached = true
+            }
+            fmt.Fprintf(out, "  %d) %s%s\n", idx+1, info.Label, marker)
         }
         fmt.Fprintf(out, "  %d) Start a new session\n", len(sessions)+1)
         fmt.Fprintln(out, "Type the number to select an option, or 'q' to cancel.")
+        if anyAttached {
+            fmt.Fprintln(out, "Add 's' to share, 'd' to detach other displays or 'D' to power detach them (e.g. 1d).")
+        }
+        fmt.Fprintln(out, "Type 'p' and a number to preview a session (e.g. p1).")
         fmt.Fprint(out, "> ")
 
         line, err := reader.ReadString('\n')
@@ -65,6 +78,19 @@ func ChooseSession(hostName string, sessions []session.Info, in io.Reader, out i
         if strings.EqualFold(trimmed, "q") {
             return Choice{Action: ActionCancel}, nil
         }
+        if rest, ok := strings.CutPrefix(trimmed, "p"); ok {
+            number, err := strconv.Atoi(strings.TrimSpace(rest))
+            if err != nil || number < 1 || number > len(sessions) {
+                fmt.Fprintln(out, "Please enter 'p' followed by a session number.")
+                continue
+            }
+            showPreview(sessions[number-1], out)
+            continue
+        }
+        mode, hasMode := parseAttachMode(trimmed[len(trimmed)-1:])
+        if hasMode {
+            trimmed = trimmed[:len(trimmed)-1]
+        }
         number, err := strconv.Atoi(trimmed)
         if err != nil {
             fmt.Fprintln(out, "Please enter a valid number.")
@@ -72,7 +98,20 @@ func ChooseSession(hostName string, sessions []session.Info, in io.Reader, out i
         }
         if number >= 1 && number <= len(sessions) {
             selected := sessions[number-1]
-            return Choice{Action: ActionAttach, SessionID: selected.ID, Label: selected.Label}, nil
+            if selected.Attached && !hasMode {
+                mode, err = promptAttachMode(selected.Label, reader, out)
+                if err != nil {
+                    if errors.Is(err, ErrCanceled) {
+                        continue
+                    }
+                    return Choice{Action: ActionCancel}, err
+                }
+            }
+            return Choice{Action: ActionAttach, SessionID: selected.ID, Label: selected.Label, Mode: mode}, nil
+        }
+        if hasMode {
+            fmt.Fprintln(out, "Attach options only apply to running sessions.")
+            continue
         }
         if number == len(sessions)+1 {
             suggestion := session.GenerateSessionLabel()
@@ -89,6 +128,51 @@ func ChooseSession(hostName string, sessions []session.Info, in io.Reader, out i
     }
 }
 
+func showPreview(info session.Info, out io.Writer) {
+    lines, err := session.Hardcopy(info.ID, previewLines)
+    if err != nil {
+        fmt.Fprintf(out, "Unable to preview %s: %v\n", info.Label, err)
+        return
+    }
+    fmt.Fprintf(out, "\n--- %s ---\n", info.Label)
+    for _, line := range lines {
+        fmt.Fprintf(out, "  %s\n", line)
+    }
+    fmt.Fprintln(out, "---")
+}
+
+func parseAttachMode(key string) (session.AttachMode, bool) {
+    switch key {
+    case "s":
+        return session.AttachShared, true
+    case "d":
+        return session.AttachSteal, true
+    case "D":
+        return session.AttachPowerDetach, true
+    }
+    return session.AttachDefault, false
+}
+
+func promptAttachMode(label string, reader *bufio.Reader, out io.Writer) (session.AttachMode, error) {
+    fmt.Fprintf(out, "\nSession %s is attached on another display.\n", label)
+    fmt.Fprintln(out, "Type 's' to share it, 'd' to detach the other display, 'D' to power detach it, or 'q' to go back.")
+    for {
+        fmt.Fprint(out, "> ")
+        line, err := reader.ReadString('\n')
+        if err != nil {
+            return session.AttachDefault, err
+        }
+        trimmed := strings.TrimSpace(line)
+        if trimmed == "" || strings.EqualFold(trimmed, "q") {
+            return session.AttachDefault, ErrCanceled
+        }
+        if mode, ok := parseAttachMode(trimmed); ok {
+            return mode, nil
+        }
+        fmt.Fprintln(out, "Please type 's', 'd', 'D' or 'q'.")
+    }
+}
+
 func promptForLabel(hostName, suggestion string, reader *bufio.Reader, out io.Writer) (string, error) {
     fmt.Fprintf(out, "\nStarting a new session for %s.\n", hostName)
     if suggestion != "" {
@@ -116,3 +200,24 @@ func promptForLabel(hostName, suggestion string, reader *bufio.Reader, out io.Wr
         return trimmed, nil
     }
 }
+
+func Confirm(question string, in io.Reader, out io.Writer) (bool, error) {
+    reader := bufio.NewReader(in)
+    for {
+        fmt.Fprintf(out, "%s [y/N] ", question)
+        line, err := reader.ReadString('\n')
+        if err != nil && line == "" {
+            if errors.Is(err, io.EOF) {
+                return false, nil
+            }
+            return false, err
+        }
+        switch strings.ToLower(strings.TrimSpace(line)) {
+        case "y", "yes":
+            return true, nil
+        case "", "n", "no":
+            return false, nil
+        }
+        fmt.Fprintln(out, "Please answer 'y' or 'n'.")
+    }
+}
@@ -0,0 +1,334 @@
+package upgrade
+
+import (
+    "archive/tar"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+)
+
+const maxDownloadSize = 256 << 20
+
+var (
+    ErrNoRelease = errors.New("no release for this platform")
+    ErrChecksum  = errors.New("checksum mismatch")
+    ErrNoBinary  = errors.New("archive does not contain a schh binary")
+)
+
+type Manifest struct {
+    Versions []Release `json:"versions"`
+}
+
+type Release struct {
+    Version string           `json:"version"`
+    Date    string           `json:"date,omitempty"`
+    Notes   string           `json:"notes,omitempty"`
+    Assets  map[string]Asset `json:"assets"`
+}
+
+type Asset struct {
+    URL    string `json:"url"`
+    SHA256 string `json:"sha256"`
+}
+
+func Platform() string {
+    return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+func LoadManifest(ctx context.Context, feed string) (Manifest, error) {
+    var manifest Manifest
+    data, err := fetch(ctx, feed)
+    if err != nil {
+        return manifest, err
+    }
+    if err := json.Unmarshal(data, &manifest); err != nil {
+        return manifest, fmt.Errorf("invalid manifest: %w", err)
+    }
+    for _, r := range manifest.Versions {
+        if _, ok := parseVersion(r.Version); !ok {
+            return manifest, fmt.Errorf("invalid manifest: bad version %q", r.Version)
+        }
+    }
+    return manifest, nil
+}
+
+func (m Manifest) Latest(platform string) (Release, Asset, error) {
+    var best Release
+    var bestAsset Asset
+    found := false
+    for _, r := range m.Versions {
+        asset, ok := r.Assets[platform]
+        if !ok || asset.URL == "" {
+            continue
+        }
+        if !found || Compare(r.Version, best.Version) > 0 {
+            best, bestAsset, found = r, asset, true
+        }
+    }
+    if !found {
+        return best, bestAsset, fmt.Errorf("%w (%s)", ErrNoRelease, platform)
+    }
+    return best, bestAsset, nil
+}
+
+func Download(ctx context.Context, feed string, asset Asset) ([]byte, error) {
+    location, err := resolve(feed, asset.URL)
+    if err != nil {
+        return nil, err
+    }
+    data, err := fetch(ctx, location)
+    if err != nil {
+        return nil, err
+    }
+    sum := sha256.Sum256(data)
+    if !strings.EqualFold(hex.EncodeToString(sum[:]), strings.TrimSpace(asset.SHA256)) {
+        return nil, fmt.Errorf("%w for %s", ErrChecksum, location)
+    }
+    if strings.HasSuffix(location, ".tar.gz") || strings.HasSuffix(location, ".tgz") {
+        return extractBinary(data)
+    }
+    return data, nil
+}
+
+func Replace(target string, data []byte) error {
+    resolved, err := filepath.EvalSymlinks(target)
+    if err != nil {
+        return err
+    }
+    info, err := os.Stat(resolved)
+    if err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(resolved), "."+filepath.Base(resolved)+".*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(info.Mode().Perm() | 0o111); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), resolved)
+}
+
+func Compare(a, b string) int {
+    va, okA := parseVersion(a)
+    vb, okB := parseVersion(b)
+    switch {
+    case !okA && !okB:
+        return 0
+    case !okA:
+        return -1
+    case !okB:
+        return 1
+    }
+    for i := 0; i < 3; i++ {
+        if c := compareInt(va.parts[i], vb.parts[i]); c != 0 {
+            return c
+        }
+    }
+    if c := comparePre(va.pre, vb.pre); c != 0 {
+        return c
+    }
+    return compareInt(va.ahead, vb.ahead)
+}
+
+func compareInt(a, b int) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    }
+    return 0
+}
+
+func comparePre(a, b string) int {
+    switch {
+    case a == b:
+        return 0
+    case a == "":
+        return 1
+    case b == "":
+        return -1
+    }
+    idsA := strings.Split(a, ".")
+    idsB := strings.Split(b, ".")
+    for i := 0; i < len(idsA) && i < len(idsB); i++ {
+        na, errA := strconv.Atoi(idsA[i])
+        nb, errB := strconv.Atoi(idsB[i])
+        switch {
+        case errA == nil && errB == nil:
+            if c := compareInt(na, nb); c != 0 {
+                return c
+            }
+        case errA == nil:
+            return -1
+        case errB == nil:
+            return 1
+        case idsA[i] != idsB[i]:
+            return strings.Compare(idsA[i], idsB[i])
+        }
+    }
+    return compareInt(len(idsA), len(idsB))
+}
+
+type semver struct {
+    parts [3]int
+    pre   string
+    ahead int
+}
+
+func parseVersion(text string) (semver, bool) {
+    var v semver
+    text = strings.TrimPrefix(strings.TrimSpace(text), "v")
+    text, _, _ = strings.Cut(text, "+")
+    text = strings.TrimSuffix(text, "-dirty")
+    text, v.ahead = cutDescribe(text)
+    text, v.pre, _ = strings.Cut(text, "-")
+    fields := strings.Split(text, ".")
+    if len(fields) == 0 || len(fields) > 3 {
+        return v, false
+    }
+    for i, field := range fields {
+        n, err := strconv.Atoi(field)
+        if err != nil || n < 0 {
+            return v, false
+        }
+        v.parts[i] = n
+    }
+    return v, true
+}
+
+func cutDescribe(text string) (string, int) {
+    rest, hash, ok := cutLast(text, "-g")
+    if !ok || hash == "" || strings.Trim(hash, "0123456789abcdef") != "" {
+        return text, 0
+    }
+    tag, count, ok := cutLast(rest, "-")
+    if !ok {
+        return text, 0
+    }
+    n, err := strconv.Atoi(count)
+    if err != nil || n < 0 {
+        return text, 0
+    }
+    return tag, n
+}
+
+func cutLast(text, sep string) (string, string, bool) {
+    i := strings.LastIndex(text, sep)
+    if i < 0 {
+        return text, "", false
+    }
+    return text[:i], text[i+len(sep):], true
+}
+
+func isURL(location string) bool {
+    return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "file://")
+}
+
+func resolve(feed, location string) (string, error) {
+    if isURL(location) {
+        return location, nil
+    }
+    if isURL(feed) {
+        base, err := url.Parse(feed)
+        if err != nil {
+            return "", err
+        }
+        ref, err := url.Parse(location)
+        if err != nil {
+            return "", err
+        }
+        return base.ResolveReference(ref).String(), nil
+    }
+    if filepath.IsAbs(location) {
+        return location, nil
+    }
+    return filepath.Join(filepath.Dir(feed), location), nil
+}
+
+func fetch(ctx context.Context, location string) ([]byte, error) {
+    if strings.HasPrefix(location, "file://") {
+        u, err := url.Parse(location)
+        if err != nil {
+            return nil, err
+        }
+        location = u.Path
+    }
+    if !isURL(location) {
+        file, err := os.Open(location)
+        if err != nil {
+            return nil, err
+        }
+        defer file.Close()
+        return readLimited(file)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("%s: %s", location, resp.Status)
+    }
+    return readLimited(resp.Body)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+    data, err := io.ReadAll(io.LimitReader(r, maxDownloadSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(data) > maxDownloadSize {
+        return nil, errors.New("download is too large")
+    }
+    return data, nil
+}
+
+func extractBinary(data []byte) ([]byte, error) {
+    gz, err := gzip.NewReader(bytes.NewReader(data))
+    if err != nil {
+        return nil, err
+    }
+    defer gz.Close()
+    archive := tar.NewReader(gz)
+    for {
+        header, err := archive.Next()
+        if errors.Is(err, io.EOF) {
+            return nil, ErrNoBinary
+        }
+        if err != nil {
+            return nil, err
+        }
+        if header.Typeflag == tar.TypeReg && path.Base(header.Name) == "schh" {
+            return readLimited(archive)
+        }
+    }
+}
@@ -0,0 +1,273 @@
+package upgrade
+
+import (
+    "archive/tar"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestCompare(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"1.2.3", "1.2.3", 0},
+        {"v1.2.3", "1.2.3", 0},
+        {"1.2", "1.2.0", 0},
+        {"1.10.0", "1.9.0", 1},
+        {"2.0.0", "1.99.99", 1},
+        {"1.2.3", "1.2.3-rc.1", 1},
+        {"1.2.3-rc.10", "1.2.3-rc.2", 1},
+        {"1.2.3-rc.2", "1.2.3-rc.10", -1},
+        {"1.2.3-beta", "1.2.3-alpha", 1},
+        {"1.2.3-alpha.1", "1.2.3-alpha", 1},
+        {"1.2.3-alpha.beta", "1.2.3-alpha.1", 1},
+        {"1.2.3+build.5", "1.2.3", 0},
+        {"v1.2.3-4-gabc1234", "v1.2.3", 1},
+        {"v1.2.3-4-gabc1234-dirty", "v1.2.3", 1},
+        {"v1.2.3-10-gabc1234", "v1.2.3-9-gdef5678", 1},
+        {"v1.2.3-4-gabc1234", "v1.2.4", -1},
+        {"v1.3.0-rc.1-2-gabc1234", "v1.3.0-rc.1", 1},
+        {"v1.3.0-rc.1-2-gabc1234", "v1.3.0", -1},
+        {"dev", "1.0.0", -1},
+        {"1.0.0", "dev", 1},
+    }
+    for _, tt := range tests {
+        if got := Compare(tt.a, tt.b); got != tt.want {
+            t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestLatest(t *testing.T) {
+    manifest := Manifest{Versions: []Release{
+        {Version: "1.2.0", Assets: map[string]Asset{"linux/amd64": {URL: "a"}, "darwin/arm64": {URL: "b"}}},
+        {Version: "1.3.0-rc.10", Assets: map[string]Asset{"linux/amd64": {URL: "c"}}},
+        {Version: "1.3.0-rc.2", Assets: map[string]Asset{"linux/amd64": {URL: "d"}}},
+        {Version: "1.4.0", Assets: map[string]Asset{"darwin/arm64": {URL: ""}}},
+    }}
+
+    release, asset, err := manifest.Latest("linux/amd64")
+    if err != nil {
+        t.Fatalf("Latest: %v", err)
+    }
+    if release.Version != "1.3.0-rc.10" || asset.URL != "c" {
+        t.Errorf("Latest(linux/amd64) = %s %s, want 1.3.0-rc.10 c", release.Version, asset.URL)
+    }
+
+    release, _, err = manifest.Latest("darwin/arm64")
+    if err != nil || release.Version != "1.2.0" {
+        t.Errorf("Latest(darwin/arm64) = %s, %v; want 1.2.0", release.Version, err)
+    }
+
+    if _, _, err := manifest.Latest("plan9/386"); !errors.Is(err, ErrNoRelease) {
+        t.Errorf("Latest(plan9/386) error = %v, want ErrNoRelease", err)
+    }
+}
+
+func TestLoadManifestRejectsBadVersion(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "releases.json")
+    writeFile(t, path, []byte(`{"versions":[{"version":"next","assets":{}}]}`))
+    if _, err := LoadManifest(context.Background(), path); err == nil {
+        t.Fatal("LoadManifest: expected an error for a bad version")
+    }
+}
+
+func TestDownloadHTTP(t *testing.T) {
+    binary := []byte("#!/bin/sh\necho schh 1.3.0\n")
+    archive := tarGz(t, map[string][]byte{"schh-1.3.0/README": []byte("readme"), "schh-1.3.0/schh": binary})
+    empty := tarGz(t, map[string][]byte{"README": []byte("readme")})
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/feed/releases.json", func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprintf(w, `{"versions":[
+            {"version":"1.2.0","assets":{"linux/amd64":{"url":"files/schh-1.2.0","sha256":"%s"}}},
+            {"version":"1.3.0","assets":{
+                "linux/amd64":{"url":"files/schh-1.3.0.tar.gz","sha256":"%s"},
+                "linux/arm64":{"url":"/other/schh","sha256":"%s"},
+                "darwin/arm64":{"url":"files/empty.tar.gz","sha256":"%s"}
+            }}
+        ]}`, sum([]byte("old")), sum(archive), sum([]byte("tampered")), sum(empty))
+    })
+    mux.HandleFunc("/feed/files/schh-1.2.0", func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("old"))
+    })
+    mux.HandleFunc("/feed/files/schh-1.3.0.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+        w.Write(archive)
+    })
+    mux.HandleFunc("/feed/files/empty.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+        w.Write(empty)
+    })
+    mux.HandleFunc("/other/schh", func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("original"))
+    })
+    server := httptest.NewServer(mux)
+    defer server.Close()
+
+    ctx := context.Background()
+    feed := server.URL + "/feed/releases.json"
+    manifest, err := LoadManifest(ctx, feed)
+    if err != nil {
+        t.Fatalf("LoadManifest: %v", err)
+    }
+
+    release, asset, err := manifest.Latest("linux/amd64")
+    if err != nil || release.Version != "1.3.0" {
+        t.Fatalf("Latest = %s, %v; want 1.3.0", release.Version, err)
+    }
+    data, err := Download(ctx, feed, asset)
+    if err != nil {
+        t.Fatalf("Download: %v", err)
+    }
+    if !bytes.Equal(data, binary) {
+        t.Errorf("Download extracted %q, want %q", data, binary)
+    }
+
+    _, asset, _ = manifest.Latest("linux/arm64")
+    if _, err := Download(ctx, feed, asset); !errors.Is(err, ErrChecksum) {
+        t.Errorf("Download with a bad checksum: error = %v, want ErrChecksum", err)
+    }
+
+    _, asset, _ = manifest.Latest("darwin/arm64")
+    if _, err := Download(ctx, feed, asset); !errors.Is(err, ErrNoBinary) {
+        t.Errorf("Download of an archive without schh: error = %v, want ErrNoBinary", err)
+    }
+
+    if _, _, err := manifest.Latest("windows/amd64"); !errors.Is(err, ErrNoRelease) {
+        t.Errorf("Latest(windows/amd64) error = %v, want ErrNoRelease", err)
+    }
+
+    if _, err := LoadManifest(ctx, server.URL+"/missing.json"); err == nil {
+        t.Error("LoadManifest of a missing feed: expected an error")
+    }
+}
+
+func TestDownloadFile(t *testing.T) {
+    dir := t.TempDir()
+    binary := []byte("new schh")
+    writeFile(t, filepath.Join(dir, "bin", "schh"), binary)
+    feed := filepath.Join(dir, "releases.json")
+    writeFile(t, feed, []byte(fmt.Sprintf(`{"versions":[{"version":"v2.0.0","assets":{"linux/amd64":{"url":"bin/schh","sha256":"%s"}}}]}`, sum(binary))))
+
+    for _, location := range []string{feed, "file://" + feed} {
+        manifest, err := LoadManifest(context.Background(), location)
+        if err != nil {
+            t.Fatalf("LoadManifest(%s): %v", location, err)
+        }
+        _, asset, err := manifest.Latest("linux/amd64")
+        if err != nil {
+            t.Fatalf("Latest: %v", err)
+        }
+        data, err := Download(context.Background(), feed, asset)
+        if err != nil {
+            t.Fatalf("Download: %v", err)
+        }
+        if !bytes.Equal(data, binary) {
+            t.Errorf("Download = %q, want %q", data, binary)
+        }
+    }
+}
+
+func TestResolve(t *testing.T) {
+    tests := []struct {
+        feed, location, want string
+    }{
+        {"https://example.com/schh/releases.json", "v1/schh.tar.gz", "https://example.com/schh/v1/schh.tar.gz"},
+        {"https://example.com/schh/releases.json", "/dl/schh", "https://example.com/dl/schh"},
+        {"/srv/schh/releases.json", "/opt/schh", "/opt/schh"},
+        {"https://example.com/schh/releases.json", "../dl/schh", "https://example.com/dl/schh"},
+        {"https://example.com/schh/releases.json", "https://cdn.example.com/schh", "https://cdn.example.com/schh"},
+        {"/srv/schh/releases.json", "bin/schh", "/srv/schh/bin/schh"},
+        {"/srv/schh/releases.json", "file:///opt/schh", "file:///opt/schh"},
+    }
+    for _, tt := range tests {
+        got, err := resolve(tt.feed, tt.location)
+        if err != nil || got != tt.want {
+            t.Errorf("resolve(%q, %q) = %q, %v; want %q", tt.feed, tt.location, got, err, tt.want)
+        }
+    }
+}
+
+func TestReplace(t *testing.T) {
+    dir := t.TempDir()
+    target := filepath.Join(dir, "schh")
+    writeFile(t, target, []byte("old"))
+    if err := os.Chmod(target, 0o640); err != nil {
+        t.Fatal(err)
+    }
+    link := filepath.Join(dir, "link")
+    if err := os.Symlink(target, link); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := Replace(link, []byte("new")); err != nil {
+        t.Fatalf("Replace: %v", err)
+    }
+    data, err := os.ReadFile(target)
+    if err != nil || string(data) != "new" {
+        t.Errorf("target = %q, %v; want new", data, err)
+    }
+    info, err := os.Lstat(link)
+    if err != nil || info.Mode()&os.ModeSymlink == 0 {
+        t.Errorf("link was replaced instead of its target")
+    }
+    info, err = os.Stat(target)
+    if err != nil || info.Mode().Perm() != 0o751 {
+        t.Errorf("target mode = %v, %v; want 0751", info.Mode().Perm(), err)
+    }
+    entries, _ := os.ReadDir(dir)
+    if len(entries) != 2 {
+        t.Errorf("Replace left %d files behind, want 2", len(entries))
+    }
+}
+
+func sum(data []byte) string {
+    digest := sha256.Sum256(data)
+    return hex.EncodeToString(digest[:])
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+    t.Helper()
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, data, 0o644); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    gz := gzip.NewWriter(&buf)
+    archive := tar.NewWriter(gz)
+    for _, name := range []string{"README", "schh-1.3.0/README", "schh-1.3.0/schh"} {
+        data, ok := files[name]
+        if !ok {
+            continue
+        }
+        if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := archive.Write(data); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := archive.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if err := gz.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return buf.Bytes()
+}
@@ -0,0 +1,74 @@
+package version
+
+import (
+    "runtime"
+    "runtime/debug"
+)
+
+var (
+    Version = "dev"
+    Commit  = ""
+    Date    = ""
+)
+
+type Info struct {
+    Version   string `json:"version"`
+    Commit    string `json:"commit,omitempty"`
+    Date      string `json:"date,omitempty"`
+    GoVersion string `json:"go_version"`
+    Platform  string `json:"platform"`
+}
+
+func Get() Info {
+    info := Info{
+        Version:   Version,
+        Commit:    Commit,
+        Date:      Date,
+        GoVersion: runtime.Version(),
+        Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+    }
+    build, ok := debug.ReadBuildInfo()
+    if !ok {
+        return info
+    }
+    if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+        info.Version = build.Main.Version
+    }
+    modified := false
+    revision := ""
+    for _, setting := range build.Settings {
+        switch setting.Key {
+        case "vcs.revision":
+            revision = setting.Value
+        case "vcs.time":
+            if info.Date == "" {
+                info.Date = setting.Value
+            }
+        case "vcs.modified":
+            modified = setting.Value == "true"
+        }
+    }
+    if info.Commit == "" && revision != "" {
+        if len(revision) > 12 {
+            revision = revision[:12]
+        }
+        if modified {
+            revision += "-dirty"
+        }
+        info.Commit = revision
+    }
+    return info
+}
+
+func (i Info) String() string {
+    text := "schh " + i.Version
+    switch {
+    case i.Commit != "" && i.Date != "":
+        text += " (" + i.Commit + ", built " + i.Date + ")"
+    case i.Commit != "":
+        text += " (" + i.Commit + ")"
+    case i.Date != "":
+        text += " (built " + i.Date + ")"
+    }
+    return text
+}